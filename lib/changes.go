@@ -5,8 +5,6 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
-	"net/url"
-	"strconv"
 )
 
 type ChangesService service
@@ -84,8 +82,6 @@ func (c *ChangesService) ApplyBulkChanges(ctx context.Context, payload ChangesBu
 }
 
 func (c *ChangesGetQuery) BuildQuery() string {
-	params := url.Values{}
-
 	if c.Page == 0 {
 		c.Page = 1
 	}
@@ -97,8 +93,7 @@ func (c *ChangesGetQuery) BuildQuery() string {
 	if c.Promoted == "" {
 		c.Promoted = "false"
 	}
-	params.Add("page", strconv.Itoa(c.Page))
-	params.Add("limit", strconv.Itoa(c.Limit))
-	params.Add("promoted", c.Promoted)
+
+	params, _ := EncodeQuery(c)
 	return params.Encode()
 }
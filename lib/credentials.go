@@ -0,0 +1,122 @@
+package lib
+
+import "encoding/json"
+
+// ChatWebhook returns the credentials of a chat provider posting to a webhook.
+func ChatWebhook(providerId ProviderIdType, webhookUrl string) SubscriberCredentialPayload {
+	return SubscriberCredentialPayload{
+		ProviderId:  providerId,
+		Credentials: Credentials{WebhookUrl: webhookUrl},
+	}
+}
+
+func SlackWebhook(webhookUrl string) SubscriberCredentialPayload {
+	return ChatWebhook(ProviderSlack, webhookUrl)
+}
+
+func DiscordWebhook(webhookUrl string) SubscriberCredentialPayload {
+	return ChatWebhook(ProviderDiscord, webhookUrl)
+}
+
+func MSTeamsWebhook(webhookUrl string) SubscriberCredentialPayload {
+	return ChatWebhook(ProviderMSTeams, webhookUrl)
+}
+
+func MattermostWebhook(webhookUrl string) SubscriberCredentialPayload {
+	return ChatWebhook(ProviderMattermost, webhookUrl)
+}
+
+func RyverWebhook(webhookUrl string) SubscriberCredentialPayload {
+	return ChatWebhook(ProviderRyver, webhookUrl)
+}
+
+func ZulipWebhook(webhookUrl string) SubscriberCredentialPayload {
+	return ChatWebhook(ProviderZulip, webhookUrl)
+}
+
+func RocketChatWebhook(webhookUrl string) SubscriberCredentialPayload {
+	return ChatWebhook(ProviderRocketChat, webhookUrl)
+}
+
+func GetStreamWebhook(webhookUrl string) SubscriberCredentialPayload {
+	return ChatWebhook(ProviderGetStream, webhookUrl)
+}
+
+// SlackChannel returns Slack credentials posting to a channel through an
+// incoming webhook.
+func SlackChannel(webhookUrl string, channel string) SubscriberCredentialPayload {
+	payload := SlackWebhook(webhookUrl)
+	payload.Credentials.Channel = channel
+	return payload
+}
+
+// GrafanaOnCallWebhook returns Grafana OnCall credentials. alertUid groups the
+// alerts of the subscriber.
+func GrafanaOnCallWebhook(webhookUrl string, alertUid string) SubscriberCredentialPayload {
+	payload := ChatWebhook(ProviderGrafanaOnCall, webhookUrl)
+	payload.Credentials.AlertUid = alertUid
+	return payload
+}
+
+// PushDeviceTokens returns the credentials of a push provider sending to the
+// given device tokens. Without tokens, the credentials clear the token list.
+func PushDeviceTokens(providerId ProviderIdType, tokens ...string) SubscriberCredentialPayload {
+	if tokens == nil {
+		tokens = []string{}
+	}
+	return SubscriberCredentialPayload{
+		ProviderId:  providerId,
+		Credentials: Credentials{DeviceTokens: tokens},
+	}
+}
+
+func FCMCredentials(tokens ...string) SubscriberCredentialPayload {
+	return PushDeviceTokens(ProviderFCM, tokens...)
+}
+
+func APNSCredentials(tokens ...string) SubscriberCredentialPayload {
+	return PushDeviceTokens(ProviderAPNS, tokens...)
+}
+
+func ExpoCredentials(tokens ...string) SubscriberCredentialPayload {
+	return PushDeviceTokens(ProviderExpo, tokens...)
+}
+
+func OneSignalCredentials(playerIds ...string) SubscriberCredentialPayload {
+	return PushDeviceTokens(ProviderOneSignal, playerIds...)
+}
+
+func PushpadCredentials(tokens ...string) SubscriberCredentialPayload {
+	return PushDeviceTokens(ProviderPushpad, tokens...)
+}
+
+func PushWebhookCredentials(tokens ...string) SubscriberCredentialPayload {
+	return PushDeviceTokens(ProviderPushWebhook, tokens...)
+}
+
+// PusherBeamsCredentials takes the user ids the subscriber is known by in
+// Pusher Beams.
+func PusherBeamsCredentials(userIds ...string) SubscriberCredentialPayload {
+	return PushDeviceTokens(ProviderPusherBeams, userIds...)
+}
+
+// WithIntegration targets a specific integration of the provider.
+func (p SubscriberCredentialPayload) WithIntegration(integrationIdentifier string) SubscriberCredentialPayload {
+	p.IntegrationIdentifier = integrationIdentifier
+	return p
+}
+
+// MarshalJSON sends an empty but non-nil DeviceTokens as an explicit empty
+// list, so that an update clears the tokens. Nil DeviceTokens are left out and
+// leave the tokens untouched.
+func (c Credentials) MarshalJSON() ([]byte, error) {
+	type credentials Credentials
+	if c.DeviceTokens == nil || len(c.DeviceTokens) > 0 {
+		return json.Marshal(credentials(c))
+	}
+
+	return json.Marshal(struct {
+		credentials
+		DeviceTokens []string `json:"deviceTokens"`
+	}{credentials(c), c.DeviceTokens})
+}
@@ -0,0 +1,39 @@
+package lib_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/saeid-a/go-novu/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredentialConstructors(t *testing.T) {
+	var expected lib.SubscriberCredentialPayload
+	fileToStruct("../testdata/update_subscriber_credentials.json", &expected)
+	expected.Credentials.DeviceTokens = nil
+
+	assert.Equal(t, expected, lib.SlackChannel("webhook", "channel").WithIntegration("123"))
+
+	assert.Equal(t, lib.SubscriberCredentialPayload{
+		ProviderId:  lib.ProviderFCM,
+		Credentials: lib.Credentials{DeviceTokens: []string{"token1", "token2"}},
+	}, lib.FCMCredentials("token1", "token2"))
+
+	assert.Equal(t, lib.SubscriberCredentialPayload{
+		ProviderId:  lib.ProviderGrafanaOnCall,
+		Credentials: lib.Credentials{WebhookUrl: "https://oncall", AlertUid: "alert"},
+	}, lib.GrafanaOnCallWebhook("https://oncall", "alert"))
+}
+
+func TestCredentials_MarshalJSON(t *testing.T) {
+	bb, err := json.Marshal(lib.FCMCredentials().WithIntegration("fcm-2"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"providerId":"fcm","integrationIdentifier":"fcm-2","credentials":{"deviceTokens":[]}}`, string(bb),
+		"an empty token list clears the tokens")
+
+	bb, err = json.Marshal(lib.SlackWebhook("webhook"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"providerId":"slack","credentials":{"webhookUrl":"webhook"}}`, string(bb))
+}
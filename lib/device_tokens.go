@@ -0,0 +1,160 @@
+package lib
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultMaxDeviceTokens     = 10
+	DefaultDeviceTokenAttempts = 3
+)
+
+// DeviceTokenOptions configures AddDeviceToken and RemoveDeviceToken.
+type DeviceTokenOptions struct {
+	// IntegrationIdentifier targets a specific integration of the provider.
+	IntegrationIdentifier string
+
+	// MaxTokens caps the tokens kept per subscriber and provider, the oldest
+	// ones being dropped first. Defaults to DefaultMaxDeviceTokens.
+	MaxTokens int
+
+	// MaxAttempts is the number of read-merge-write rounds tried before giving
+	// up when a concurrent update overwrites ours. Defaults to
+	// DefaultDeviceTokenAttempts.
+	MaxAttempts int
+}
+
+// AddDeviceToken appends a device token to the subscriber credentials of a push
+// provider and returns the stored tokens.
+//
+// Novu replaces the whole token list on update, so the current credentials
+// are read, merged and written back, then read again to check that no
+// concurrent update dropped the token. The round is retried when it did.
+func (s *SubscriberService) AddDeviceToken(ctx context.Context, subscriberID string, providerId ProviderIdType, token string, opts *DeviceTokenOptions) ([]string, error) {
+	o := deviceTokenOptions(opts)
+
+	return s.updateDeviceTokens(ctx, subscriberID, providerId, o, func(tokens []string) []string {
+		tokens = append(removeToken(tokens, token), token)
+		if len(tokens) > o.MaxTokens {
+			tokens = tokens[len(tokens)-o.MaxTokens:]
+		}
+		return tokens
+	}, func(tokens []string) bool {
+		return containsToken(tokens, token)
+	})
+}
+
+// RemoveDeviceToken removes a device token from the subscriber credentials of
+// a push provider and returns the remaining tokens.
+func (s *SubscriberService) RemoveDeviceToken(ctx context.Context, subscriberID string, providerId ProviderIdType, token string, opts *DeviceTokenOptions) ([]string, error) {
+	return s.updateDeviceTokens(ctx, subscriberID, providerId, deviceTokenOptions(opts), func(tokens []string) []string {
+		return removeToken(tokens, token)
+	}, func(tokens []string) bool {
+		return !containsToken(tokens, token)
+	})
+}
+
+func deviceTokenOptions(opts *DeviceTokenOptions) DeviceTokenOptions {
+	var o DeviceTokenOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.MaxTokens <= 0 {
+		o.MaxTokens = DefaultMaxDeviceTokens
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = DefaultDeviceTokenAttempts
+	}
+
+	return o
+}
+
+func (s *SubscriberService) updateDeviceTokens(ctx context.Context, subscriberID string, providerId ProviderIdType, opts DeviceTokenOptions, merge func([]string) []string, applied func([]string) bool) ([]string, error) {
+	tokens, err := s.deviceTokens(ctx, subscriberID, providerId, opts.IntegrationIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; attempt < opts.MaxAttempts; attempt++ {
+		merged := merge(dedupeTokens(tokens))
+		if !tokensEqual(merged, tokens) {
+			payload := PushDeviceTokens(providerId, merged...).WithIntegration(opts.IntegrationIdentifier)
+			if _, err := s.UpdateCredentials(ctx, subscriberID, payload); err != nil {
+				return nil, err
+			}
+		}
+
+		if tokens, err = s.deviceTokens(ctx, subscriberID, providerId, opts.IntegrationIdentifier); err != nil {
+			return nil, err
+		}
+		if applied(tokens) {
+			return tokens, nil
+		}
+	}
+
+	return tokens, errors.Errorf("device tokens of %s kept changing after %d attempts", subscriberID, opts.MaxAttempts)
+}
+
+func (s *SubscriberService) deviceTokens(ctx context.Context, subscriberID string, providerId ProviderIdType, integrationIdentifier string) ([]string, error) {
+	resp, err := s.Get(ctx, subscriberID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get credentials of %s", subscriberID)
+	}
+
+	for _, channel := range resp.Data.Channels {
+		if channel.ProviderId != providerId {
+			continue
+		}
+		if integrationIdentifier != "" && channel.IntegrationIdentifier != integrationIdentifier {
+			continue
+		}
+		return channel.Credentials.DeviceTokens, nil
+	}
+
+	return nil, nil
+}
+
+func containsToken(tokens []string, token string) bool {
+	for _, t := range tokens {
+		if t == token {
+			return true
+		}
+	}
+	return false
+}
+
+func removeToken(tokens []string, token string) []string {
+	out := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		if t != token {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func dedupeTokens(tokens []string) []string {
+	seen := make(map[string]bool, len(tokens))
+	out := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		if t != "" && !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func tokensEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package lib_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/saeid-a/go-novu/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tokenServer stores the fcm tokens of a single subscriber in tokens and
+// records every write. clobber, when set, replaces the tokens right after the
+// first write to simulate a concurrent update from another device.
+func tokenServer(t *testing.T, tokens []string, clobber []string, writes *[][]string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodPut {
+			var body lib.SubscriberCredentialPayload
+			_ = json.NewDecoder(req.Body).Decode(&body)
+			*writes = append(*writes, body.Credentials.DeviceTokens)
+			tokens = body.Credentials.DeviceTokens
+			if clobber != nil {
+				tokens, clobber = clobber, nil
+			}
+		}
+
+		resp := lib.SubscriberResponse{Data: lib.Subscriber{SubscriberId: subscriberID, Channels: []lib.SubscriberChannel{
+			{ProviderId: lib.ProviderFCM, Credentials: lib.Credentials{DeviceTokens: tokens}},
+		}}}
+		bb, _ := json.Marshal(resp)
+		w.Write(bb)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestSubscriberService_AddDeviceToken(t *testing.T) {
+	var writes [][]string
+	httpServer := tokenServer(t, []string{"a", "b", "b"}, nil, &writes)
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+
+	tokens, err := c.SubscriberApi.AddDeviceToken(context.Background(), subscriberID, lib.ProviderFCM, "c", &lib.DeviceTokenOptions{MaxTokens: 2})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"b", "c"}, tokens)
+	assert.Equal(t, [][]string{{"b", "c"}}, writes)
+}
+
+func TestSubscriberService_AddDeviceToken_AlreadyStored(t *testing.T) {
+	var writes [][]string
+	httpServer := tokenServer(t, []string{"a", "b"}, nil, &writes)
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+
+	tokens, err := c.SubscriberApi.AddDeviceToken(context.Background(), subscriberID, lib.ProviderFCM, "b", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"a", "b"}, tokens)
+	assert.Empty(t, writes)
+}
+
+func TestSubscriberService_AddDeviceToken_ConcurrentUpdate(t *testing.T) {
+	var writes [][]string
+	httpServer := tokenServer(t, []string{"a"}, []string{"a", "other"}, &writes)
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+
+	tokens, err := c.SubscriberApi.AddDeviceToken(context.Background(), subscriberID, lib.ProviderFCM, "mine", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"a", "other", "mine"}, tokens)
+	assert.Len(t, writes, 2)
+}
+
+func TestSubscriberService_RemoveDeviceToken(t *testing.T) {
+	var writes [][]string
+	httpServer := tokenServer(t, []string{"a"}, nil, &writes)
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+
+	tokens, err := c.SubscriberApi.RemoveDeviceToken(context.Background(), subscriberID, lib.ProviderFCM, "a", nil)
+	require.NoError(t, err)
+
+	assert.Empty(t, tokens)
+	require.Len(t, writes, 1)
+	assert.NotNil(t, writes[0])
+	assert.Empty(t, writes[0])
+}
@@ -3,6 +3,8 @@ package lib
 import (
 	"context"
 	"fmt"
+
+	"github.com/pkg/errors"
 )
 
 type IEnvironment interface {
@@ -19,6 +21,10 @@ func (e EnvironmentService) Trigger(ctx context.Context, eventId string, data IT
 	return EventResponse{}, nil
 }
 
+func (e EnvironmentService) TriggerTopics(ctx context.Context, eventId string, opts MultiTopicTriggerOptions) (*MultiTopicTriggerReport, error) {
+	return nil, errors.New("environment TriggerTopics is not implemented")
+}
+
 func (e EnvironmentService) TriggerBulk(ctx context.Context, data []BulkTriggerOptions) ([]EventResponse, error) {
 	//TODO implement me
 	fmt.Println("implement me")
@@ -4,11 +4,28 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"reflect"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultMaxTriggerRecipients is the number of subscribers Novu accepts in
+	// the "to" field of a single trigger.
+	DefaultMaxTriggerRecipients = 100
+
+	// maxBulkTriggerEvents is the number of events Novu accepts in a single
+	// bulk trigger request.
+	maxBulkTriggerEvents = 100
 )
 
 type IEvent interface {
 	Trigger(ctx context.Context, eventId string, data ITriggerPayloadOptions) (EventResponse, error)
+	TriggerTopics(ctx context.Context, eventId string, opts MultiTopicTriggerOptions) (*MultiTopicTriggerReport, error)
 	TriggerBulk(ctx context.Context, data []BulkTriggerOptions) ([]EventResponse, error)
 	BroadcastToAll(ctx context.Context, data BroadcastEventToAll) (EventResponse, error)
 	CancelTrigger(ctx context.Context, transactionId string) (bool, error)
@@ -16,7 +33,22 @@ type IEvent interface {
 
 type EventService service
 
+// Trigger sends the event to the recipients in data.To. When data.To is a list
+// larger than Config.MaxTriggerRecipients it is split into chunks that are sent
+// through the bulk trigger endpoint, each chunk getting a transactionId derived
+// from data.TransactionId. The returned response then carries a
+// FanOutTriggerData aggregating the result of every chunk.
+//
+// When Config.ScrubPolicy is set it is applied to data before anything is sent.
 func (e *EventService) Trigger(ctx context.Context, eventId string, data ITriggerPayloadOptions) (EventResponse, error) {
+	if err := e.client.scrub(&data); err != nil {
+		return EventResponse{}, err
+	}
+
+	if chunks := splitRecipients(data.To, e.client.maxTriggerRecipients()); len(chunks) > 1 {
+		return e.triggerFanOut(ctx, eventId, data, chunks)
+	}
+
 	var resp EventResponse
 	URL := e.client.config.BackendURL.JoinPath("events/trigger")
 
@@ -46,11 +78,26 @@ func (e *EventService) Trigger(ctx context.Context, eventId string, data ITrigge
 }
 
 func (e *EventService) TriggerBulk(ctx context.Context, data []BulkTriggerOptions) ([]EventResponse, error) {
+	events := make([]BulkTriggerOptions, 0, len(data))
+	for _, event := range data {
+		scrubbed := ITriggerPayloadOptions{To: event.To, Payload: event.Payload, Overrides: event.Overrides}
+		if err := e.client.scrub(&scrubbed); err != nil {
+			return nil, err
+		}
+		event.To, event.Payload, event.Overrides = scrubbed.To, scrubbed.Payload, scrubbed.Overrides
+		events = append(events, event)
+	}
+
+	return e.triggerBulk(ctx, events)
+}
+
+// triggerBulk sends events already scrubbed.
+func (e *EventService) triggerBulk(ctx context.Context, events []BulkTriggerOptions) ([]EventResponse, error) {
 	var resp []EventResponse
 	URL := e.client.config.BackendURL.JoinPath("events/trigger/bulk")
 
 	reqBody := BulkTriggerEvent{
-		Events: data,
+		Events: events,
 	}
 
 	jsonBody, _ := json.Marshal(reqBody)
@@ -73,10 +120,15 @@ func (e *EventService) BroadcastToAll(ctx context.Context, data BroadcastEventTo
 	var resp EventResponse
 	URL := e.client.config.BackendURL.JoinPath("events/trigger/broadcast")
 
+	scrubbed := ITriggerPayloadOptions{Payload: data.Payload, Overrides: data.Overrides}
+	if err := e.client.scrub(&scrubbed); err != nil {
+		return resp, err
+	}
+
 	reqBody := BroadcastEventToAll{
 		Name:          data.Name,
-		Payload:       data.Payload,
-		Overrides:     data.Overrides,
+		Payload:       scrubbed.Payload,
+		Overrides:     scrubbed.Overrides,
 		TransactionId: data.TransactionId,
 		Actor:         data.Actor,
 	}
@@ -113,4 +165,192 @@ func (e *EventService) CancelTrigger(ctx context.Context, transactionId string)
 	return resp, nil
 }
 
+// TriggerTopics expands opts.TopicKeys into their subscribers and triggers the
+// event once for the de-duplicated union, so subscribers that belong to several
+// topics are only notified once.
+func (e *EventService) TriggerTopics(ctx context.Context, eventId string, opts MultiTopicTriggerOptions) (*MultiTopicTriggerReport, error) {
+	excluded := make(map[string]bool, len(opts.Exclude)+1)
+	for _, subscriberId := range opts.Exclude {
+		excluded[subscriberId] = true
+	}
+	if opts.ExcludeActor {
+		if actorId := recipientSubscriberId(opts.Trigger.Actor); actorId != "" {
+			excluded[actorId] = true
+		}
+	}
+
+	report := &MultiTopicTriggerReport{Recipients: make(map[string][]string)}
+	recipients := make([]string, 0)
+	skipped := make(map[string]bool)
+
+	for _, key := range opts.TopicKeys {
+		topic, err := e.client.TopicsApi.Get(ctx, key)
+		if err != nil {
+			return report, errors.Wrapf(err, "unable to get topic %s", key)
+		}
+
+		for _, subscriberId := range topic.Subscribers {
+			if excluded[subscriberId] {
+				if !skipped[subscriberId] {
+					skipped[subscriberId] = true
+					report.Excluded = append(report.Excluded, subscriberId)
+				}
+				continue
+			}
+
+			topics, seen := report.Recipients[subscriberId]
+			if !seen {
+				recipients = append(recipients, subscriberId)
+			}
+			if len(topics) == 0 || topics[len(topics)-1] != key {
+				report.Recipients[subscriberId] = append(topics, key)
+			}
+		}
+	}
+	sort.Strings(report.Excluded)
+
+	if len(recipients) == 0 {
+		return report, nil
+	}
+
+	data := opts.Trigger
+	data.To = recipients
+
+	resp, err := e.Trigger(ctx, eventId, data)
+	report.Response = resp
+	if err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+func (e *EventService) triggerFanOut(ctx context.Context, eventId string, data ITriggerPayloadOptions, chunks []interface{}) (EventResponse, error) {
+	baseTransactionId := data.TransactionId
+	if baseTransactionId == "" {
+		baseTransactionId = uuid.New().String()
+	}
+
+	events := make([]BulkTriggerOptions, 0, len(chunks))
+	for i, to := range chunks {
+		events = append(events, BulkTriggerOptions{
+			Name:          eventId,
+			To:            to,
+			Payload:       data.Payload,
+			Overrides:     data.Overrides,
+			TransactionId: fmt.Sprintf("%s-%d", baseTransactionId, i),
+			Actor:         data.Actor,
+			Tenant:        data.Tenant,
+		})
+	}
+
+	result := FanOutTriggerData{
+		Acknowledged:   true,
+		TransactionId:  baseTransactionId,
+		TransactionIds: make([]string, 0, len(events)),
+		Responses:      make([]interface{}, 0, len(events)),
+	}
+
+	for start := 0; start < len(events); start += maxBulkTriggerEvents {
+		end := start + maxBulkTriggerEvents
+		if end > len(events) {
+			end = len(events)
+		}
+
+		// data was scrubbed by Trigger before it was split.
+		responses, err := e.triggerBulk(ctx, events[start:end])
+		if err != nil {
+			result.Acknowledged = false
+			result.Status = fanOutStatus(result.Responses)
+			return EventResponse{JsonResponse{Data: result}}, errors.Wrapf(err, "fan-out trigger failed after %d of %d chunks", start, len(events))
+		}
+
+		for _, event := range events[start:end] {
+			result.TransactionIds = append(result.TransactionIds, event.TransactionId)
+		}
+		for _, r := range responses {
+			result.Responses = append(result.Responses, r.Data)
+			if acknowledged, _ := responseField(r.Data, "acknowledged").(bool); !acknowledged {
+				result.Acknowledged = false
+			}
+		}
+	}
+	result.Status = fanOutStatus(result.Responses)
+
+	return EventResponse{JsonResponse{Data: result}}, nil
+}
+
+// splitRecipients splits a recipient list into chunks of at most size
+// elements, keeping the element type of the original slice. It returns nil
+// when to is not a list or does not need to be split. Topic recipients are
+// never split since each one already stands for many subscribers.
+func splitRecipients(to interface{}, size int) []interface{} {
+	if _, ok := to.([]TriggerTopicRecipientsTypeSingle); ok {
+		return nil
+	}
+
+	v := reflect.ValueOf(to)
+	if v.Kind() != reflect.Slice || v.Len() <= size {
+		return nil
+	}
+
+	chunks := make([]interface{}, 0, (v.Len()+size-1)/size)
+	for start := 0; start < v.Len(); start += size {
+		end := start + size
+		if end > v.Len() {
+			end = v.Len()
+		}
+		chunks = append(chunks, v.Slice(start, end).Interface())
+	}
+
+	return chunks
+}
+
+// fanOutStatus returns the status shared by all chunk responses, or "mixed"
+// when they disagree.
+func fanOutStatus(responses []interface{}) string {
+	status := ""
+	for _, r := range responses {
+		s, _ := responseField(r, "status").(string)
+		if status != "" && s != status {
+			return "mixed"
+		}
+		status = s
+	}
+
+	return status
+}
+
+// recipientSubscriberId returns the subscriber id of a recipient given either as
+// a plain id, a SubscriberPayload or a map with a "subscriberId" key.
+func recipientSubscriberId(recipient interface{}) string {
+	switch r := recipient.(type) {
+	case string:
+		return r
+	case SubscriberPayload:
+		return r.SubscriberId
+	case *SubscriberPayload:
+		if r != nil {
+			return r.SubscriberId
+		}
+	case map[string]interface{}:
+		if id, ok := r["subscriberId"].(string); ok {
+			return id
+		}
+	case map[string]string:
+		return r["subscriberId"]
+	}
+
+	return ""
+}
+
+func responseField(data interface{}, key string) interface{} {
+	m, ok := data.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	return m[key]
+}
+
 var _ IEvent = &EventService{}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
@@ -265,3 +266,136 @@ func TestCancelTriggeredEvent_Success(t *testing.T) {
 		assert.Equal(t, expectedResponse, resp)
 	})
 }
+
+func TestEventServiceTrigger_FanOut_Success(t *testing.T) {
+	var (
+		receivedEvents []lib.BulkTriggerOptions
+		requests       int
+	)
+
+	eventService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var receivedBody lib.BulkTriggerEvent
+		if err := json.NewDecoder(req.Body).Decode(&receivedBody); err != nil {
+			log.Printf("error in unmarshalling %+v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		requests++
+
+		t.Run("URL and request method is as expected", func(t *testing.T) {
+			expectedURL := "/v1/events/trigger/bulk"
+			assert.Equal(t, http.MethodPost, req.Method)
+			assert.Equal(t, expectedURL, req.RequestURI)
+		})
+
+		resp := make([]lib.EventResponse, 0, len(receivedBody.Events))
+		for _, event := range receivedBody.Events {
+			receivedEvents = append(receivedEvents, event)
+			resp = append(resp, lib.EventResponse{JsonResponse: lib.JsonResponse{Data: map[string]interface{}{
+				"acknowledged":  true,
+				"status":        "processed",
+				"transactionId": event.TransactionId,
+			}}})
+		}
+
+		w.WriteHeader(http.StatusOK)
+		bb, _ := json.Marshal(resp)
+		w.Write(bb)
+	}))
+
+	defer eventService.Close()
+
+	to := make([]string, 0, 25)
+	for i := 0; i < 25; i++ {
+		to = append(to, fmt.Sprintf("subscriber-%d", i))
+	}
+
+	ctx := context.Background()
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{
+		BackendURL:           lib.MustParseURL(eventService.URL),
+		MaxTriggerRecipients: 10,
+	})
+	resp, err := c.EventApi.Trigger(ctx, novuEventId, lib.ITriggerPayloadOptions{
+		To:            to,
+		Payload:       map[string]interface{}{"name": "Hello World"},
+		TransactionId: "announcement",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, requests)
+	require.Len(t, receivedEvents, 3)
+	assert.Len(t, receivedEvents[0].To, 10)
+	assert.Len(t, receivedEvents[1].To, 10)
+	assert.Len(t, receivedEvents[2].To, 5)
+	assert.Equal(t, novuEventId, receivedEvents[0].Name)
+
+	data, ok := resp.Data.(lib.FanOutTriggerData)
+	require.True(t, ok)
+	assert.True(t, data.Acknowledged)
+	assert.Equal(t, "processed", data.Status)
+	assert.Equal(t, "announcement", data.TransactionId)
+	assert.Equal(t, []string{"announcement-0", "announcement-1", "announcement-2"}, data.TransactionIds)
+	assert.Len(t, data.Responses, 3)
+}
+
+func TestEventServiceTriggerTopics_Success(t *testing.T) {
+	var receivedBody lib.EventRequest
+
+	topics := map[string][]string{
+		"project-members": {"alice", "bob", "carol"},
+		"org-admins":      {"bob", "dave", "erin"},
+	}
+
+	eventService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if strings.HasPrefix(req.RequestURI, "/v1/topics/") {
+			assert.Equal(t, http.MethodGet, req.Method)
+			key := strings.TrimPrefix(req.RequestURI, "/v1/topics/")
+
+			w.WriteHeader(http.StatusOK)
+			bb, _ := json.Marshal(lib.GetTopicResponse{Key: key, Subscribers: topics[key]})
+			w.Write(bb)
+			return
+		}
+
+		t.Run("URL and request method is as expected", func(t *testing.T) {
+			assert.Equal(t, http.MethodPost, req.Method)
+			assert.Equal(t, "/v1/events/trigger", req.RequestURI)
+		})
+
+		if err := json.NewDecoder(req.Body).Decode(&receivedBody); err != nil {
+			log.Printf("error in unmarshalling %+v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var resp lib.EventResponse
+		fileToStruct(filepath.Join("../testdata", "novu_send_trigger_response.json"), &resp)
+
+		w.WriteHeader(http.StatusOK)
+		bb, _ := json.Marshal(resp)
+		w.Write(bb)
+	}))
+
+	defer eventService.Close()
+
+	ctx := context.Background()
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(eventService.URL)})
+	report, err := c.EventApi.TriggerTopics(ctx, novuEventId, lib.MultiTopicTriggerOptions{
+		TopicKeys:    []string{"project-members", "org-admins"},
+		Exclude:      []string{"erin"},
+		ExcludeActor: true,
+		Trigger: lib.ITriggerPayloadOptions{
+			Payload: map[string]interface{}{"name": "Hello World"},
+			Actor:   lib.SubscriberPayload{SubscriberId: "carol"},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []interface{}{"alice", "bob", "dave"}, receivedBody.To)
+	assert.Equal(t, map[string][]string{
+		"alice": {"project-members"},
+		"bob":   {"project-members", "org-admins"},
+		"dave":  {"org-admins"},
+	}, report.Recipients)
+	assert.Equal(t, []string{"carol", "erin"}, report.Excluded)
+}
@@ -3,7 +3,6 @@ package lib
 import (
 	"context"
 	"net/http"
-	"net/url"
 )
 
 type ExecutionsService service
@@ -24,12 +23,6 @@ func (e *ExecutionsService) GetExecutions(ctx context.Context, q QueryBuilder) (
 }
 
 func (q ExecutionsQueryParams) BuildQuery() string {
-	params := url.Values{}
-	if q.NotificationId != "" {
-		params.Add("notificationId", q.NotificationId)
-	}
-	if q.SubscriberId != "" {
-		params.Add("subscriberId", q.SubscriberId)
-	}
+	params, _ := EncodeQuery(q)
 	return params.Encode()
 }
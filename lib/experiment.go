@@ -0,0 +1,123 @@
+package lib
+
+import (
+	"context"
+	"hash/fnv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// ExperimentPayloadKey is the payload key holding the experiment and variant a
+// trigger was sent for.
+const ExperimentPayloadKey = "experiment"
+
+const experimentTransactionSeparator = ":"
+
+// ExperimentVariant is one arm of an Experiment, sent through its own workflow.
+type ExperimentVariant struct {
+	Name     string
+	Workflow string
+	Weight   int
+}
+
+// ExperimentAssignment is what ends up in the payload of an experiment trigger.
+type ExperimentAssignment struct {
+	Experiment string `json:"name"`
+	Variant    string `json:"variant"`
+}
+
+// Experiment splits subscribers between workflow variants. A subscriber always
+// lands in the same variant for a given experiment name.
+type Experiment struct {
+	client      *APIClient
+	name        string
+	variants    []ExperimentVariant
+	totalWeight uint64
+}
+
+func NewExperiment(client *APIClient, name string, variants ...ExperimentVariant) (*Experiment, error) {
+	if name == "" || strings.Contains(name, experimentTransactionSeparator) {
+		return nil, errors.Errorf("invalid experiment name %q", name)
+	}
+
+	x := &Experiment{client: client, name: name, variants: variants}
+	for _, v := range variants {
+		if v.Name == "" || strings.Contains(v.Name, experimentTransactionSeparator) {
+			return nil, errors.Errorf("invalid variant name %q", v.Name)
+		}
+		if v.Workflow == "" {
+			return nil, errors.Errorf("variant %s has no workflow", v.Name)
+		}
+		if v.Weight < 0 {
+			return nil, errors.Errorf("variant %s has a negative weight", v.Name)
+		}
+		x.totalWeight += uint64(v.Weight)
+	}
+	if x.totalWeight == 0 {
+		return nil, errors.New("experiment needs at least one variant with a positive weight")
+	}
+
+	return x, nil
+}
+
+// Choose returns the variant of the subscriber.
+func (x *Experiment) Choose(subscriberID string) ExperimentVariant {
+	h := fnv.New64a()
+	h.Write([]byte(x.name + experimentTransactionSeparator + subscriberID))
+	bucket := h.Sum64() % x.totalWeight
+
+	for _, v := range x.variants {
+		if bucket < uint64(v.Weight) {
+			return v
+		}
+		bucket -= uint64(v.Weight)
+	}
+
+	// unreachable, the buckets cover the total weight
+	return x.variants[len(x.variants)-1]
+}
+
+// Trigger sends the workflow of the subscriber variant. The assignment is added
+// to the payload under ExperimentPayloadKey and, unless data.TransactionId is
+// set, encoded in the transactionId (see ParseExperimentTransactionId) so it can
+// be found again through MessagesApi. data.To defaults to the subscriber id.
+func (x *Experiment) Trigger(ctx context.Context, subscriberID string, data ITriggerPayloadOptions) (EventResponse, ExperimentVariant, error) {
+	variant := x.Choose(subscriberID)
+	assignment := ExperimentAssignment{Experiment: x.name, Variant: variant.Name}
+
+	payload, err := x.client.mergeStruct(data.Payload, map[string]interface{}{ExperimentPayloadKey: assignment})
+	if err != nil {
+		return EventResponse{}, variant, errors.Wrap(err, "unable to merge payload")
+	}
+	data.Payload = payload
+
+	if data.To == nil {
+		data.To = subscriberID
+	}
+	if data.TransactionId == "" {
+		data.TransactionId = strings.Join([]string{x.name, variant.Name, uuid.New().String()}, experimentTransactionSeparator)
+	}
+
+	resp, err := x.client.EventApi.Trigger(ctx, variant.Workflow, data)
+	if err != nil {
+		return resp, variant, errors.Wrapf(err, "unable to trigger variant %s", variant.Name)
+	}
+
+	return resp, variant, nil
+}
+
+// ParseExperimentTransactionId returns the assignment encoded in a transactionId
+// generated by Experiment.Trigger.
+func ParseExperimentTransactionId(transactionId string) (ExperimentAssignment, bool) {
+	parts := strings.Split(transactionId, experimentTransactionSeparator)
+	if len(parts) != 3 {
+		return ExperimentAssignment{}, false
+	}
+	if _, err := uuid.Parse(parts[2]); err != nil {
+		return ExperimentAssignment{}, false
+	}
+
+	return ExperimentAssignment{Experiment: parts[0], Variant: parts[1]}, true
+}
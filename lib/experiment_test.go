@@ -0,0 +1,90 @@
+package lib_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/saeid-a/go-novu/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExperiment_Choose(t *testing.T) {
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{})
+
+	t.Run("Invalid experiments are rejected", func(t *testing.T) {
+		_, err := lib.NewExperiment(c, "welcome")
+		assert.Error(t, err)
+		_, err = lib.NewExperiment(c, "welcome", lib.ExperimentVariant{Name: "a", Workflow: "welcome-a"})
+		assert.Error(t, err)
+		_, err = lib.NewExperiment(c, "wel:come", lib.ExperimentVariant{Name: "a", Workflow: "welcome-a", Weight: 1})
+		assert.Error(t, err)
+	})
+
+	x, err := lib.NewExperiment(c, "welcome",
+		lib.ExperimentVariant{Name: "control", Workflow: "welcome-a", Weight: 3},
+		lib.ExperimentVariant{Name: "short-copy", Workflow: "welcome-b", Weight: 1},
+		lib.ExperimentVariant{Name: "disabled", Workflow: "welcome-c", Weight: 0},
+	)
+	require.NoError(t, err)
+
+	counts := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		subscriberID := fmt.Sprintf("subscriber-%d", i)
+		variant := x.Choose(subscriberID)
+		assert.Equal(t, variant, x.Choose(subscriberID))
+		counts[variant.Name]++
+	}
+
+	assert.Zero(t, counts["disabled"])
+	assert.InDelta(t, 3000, counts["control"], 200)
+	assert.InDelta(t, 1000, counts["short-copy"], 200)
+}
+
+func TestExperiment_Trigger_Success(t *testing.T) {
+	var receivedBody lib.EventRequest
+
+	eventService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assureRequestHeaders(t, req, "/v1/events/trigger", http.MethodPost)
+
+		if err := json.NewDecoder(req.Body).Decode(&receivedBody); err != nil {
+			log.Printf("error in unmarshalling %+v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"data":{"acknowledged":true,"status":"processed"}}`))
+	}))
+	defer eventService.Close()
+
+	ctx := context.Background()
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(eventService.URL)})
+	x, err := lib.NewExperiment(c, "welcome",
+		lib.ExperimentVariant{Name: "control", Workflow: "welcome-a", Weight: 1},
+		lib.ExperimentVariant{Name: "short-copy", Workflow: "welcome-b", Weight: 1},
+	)
+	require.NoError(t, err)
+
+	_, variant, err := x.Trigger(ctx, subscriberID, lib.ITriggerPayloadOptions{
+		Payload: map[string]interface{}{"name": "John"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, x.Choose(subscriberID), variant)
+	assert.Equal(t, variant.Workflow, receivedBody.Name)
+	assert.Equal(t, subscriberID, receivedBody.To)
+	assert.Equal(t, map[string]interface{}{
+		"name":       "John",
+		"experiment": map[string]interface{}{"name": "welcome", "variant": variant.Name},
+	}, receivedBody.Payload)
+
+	assignment, ok := lib.ParseExperimentTransactionId(receivedBody.TransactionId)
+	require.True(t, ok)
+	assert.Equal(t, lib.ExperimentAssignment{Experiment: "welcome", Variant: variant.Name}, assignment)
+}
@@ -39,12 +39,14 @@ func (l *LayoutService) Create(ctx context.Context, request CreateLayoutRequest)
 func (l *LayoutService) List(ctx context.Context, options *LayoutRequestOptions) (*LayoutsResponse, error) {
 	var resp LayoutsResponse
 	URL := l.client.config.BackendURL.JoinPath("layouts")
-	if options == nil {
-		options = &LayoutRequestOptions{}
+
+	query, err := EncodeQuery(options)
+	if err != nil {
+		return nil, err
 	}
-	queryParams, _ := json.Marshal(options)
+	URL.RawQuery = query.Encode()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, URL.String(), bytes.NewBuffer(queryParams))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, URL.String(), http.NoBody)
 	if err != nil {
 		return nil, err
 	}
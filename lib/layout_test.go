@@ -89,6 +89,22 @@ func TestLayoutService_List_Layouts_Success(t *testing.T) {
 	require.Equal(t, expectedResponse, resp)
 }
 
+func TestLayoutService_List_Layouts_Options(t *testing.T) {
+	page, pageSize, key, orderBy := 1, 10, "layoutKey", -1
+	httpServer := createTestServer(t, TestServerOptions[map[string]string, lib.LayoutsResponse]{
+		expectedURLPath:    "/v1/layouts?key=layoutKey&orderBy=-1&page=1&pageSize=10",
+		expectedSentMethod: http.MethodGet,
+		responseStatusCode: http.StatusOK,
+		responseBody:       lib.LayoutsResponse{Page: page, PageSize: pageSize},
+	})
+
+	ctx := context.Background()
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+	_, err := c.LayoutApi.List(ctx, &lib.LayoutRequestOptions{Page: &page, PageSize: &pageSize, Key: &key, OrderBy: &orderBy})
+
+	require.NoError(t, err)
+}
+
 func TestLayoutService_Get_Layout_Success(t *testing.T) {
 
 	var expectedResponse *lib.LayoutResponse = &lib.LayoutResponse{
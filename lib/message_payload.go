@@ -0,0 +1,58 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// MessagePayload is the trigger payload a message was rendered with, kept as
+// raw JSON since every workflow defines its own.
+type MessagePayload json.RawMessage
+
+func (p MessagePayload) MarshalJSON() ([]byte, error) {
+	if len(p) == 0 {
+		return []byte("null"), nil
+	}
+
+	return p, nil
+}
+
+// UnmarshalJSON keeps a compacted copy of the payload, so that payloads
+// compare equal regardless of the formatting they were received with.
+func (p *MessagePayload) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(bytes.TrimSpace(b), []byte("null")) {
+		*p = nil
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, b); err != nil {
+		return err
+	}
+	*p = buf.Bytes()
+
+	return nil
+}
+
+// Map decodes the payload into a generic map.
+func (p MessagePayload) Map() (map[string]interface{}, error) {
+	return DecodePayload[map[string]interface{}](p)
+}
+
+// DecodePayload decodes a message payload into T, e.g.
+//
+//	payload, err := lib.DecodePayload[InvitePayload](message.Payload)
+func DecodePayload[T any](payload MessagePayload) (T, error) {
+	var v T
+	if len(payload) == 0 {
+		return v, nil
+	}
+
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return v, errors.Wrap(err, "unable to decode message payload")
+	}
+
+	return v, nil
+}
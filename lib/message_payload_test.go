@@ -0,0 +1,49 @@
+package lib_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/saeid-a/go-novu/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodePayload(t *testing.T) {
+	var feed lib.SubscriberNotificationFeedResponse
+	fileToStruct(filepath.Join("../testdata", "subscriber_notification_feed_response.json"), &feed)
+	require.Len(t, feed.Data, 1)
+	message := feed.Data[0]
+
+	type teamPayload struct {
+		UpdateMessage string `json:"updateMessage"`
+		Team          struct {
+			Name    string `json:"name"`
+			Members int    `json:"members"`
+		} `json:"team"`
+	}
+
+	payload, err := lib.DecodePayload[teamPayload](message.Payload)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello wassup", payload.UpdateMessage)
+	assert.Equal(t, "Core", payload.Team.Name)
+	assert.Equal(t, 4, payload.Team.Members)
+
+	m, err := message.Payload.Map()
+	require.NoError(t, err)
+	assert.Equal(t, "Hello wassup", m["updateMessage"])
+
+	assert.Equal(t, &lib.MessageActor{Type: lib.ActorUser}, message.Actor)
+	assert.Equal(t, "alice", message.ActorSubscriber.SubscriberID)
+	assert.Equal(t, "https://example.com/alice.png", message.Avatar)
+	assert.Equal(t, map[string]interface{}{"in_app": map[string]interface{}{"priority": "high"}}, message.Overrides)
+}
+
+func TestDecodePayload_Empty(t *testing.T) {
+	payload, err := lib.DecodePayload[map[string]interface{}](nil)
+	require.NoError(t, err)
+	assert.Nil(t, payload)
+
+	_, err = lib.DecodePayload[int](lib.MessagePayload(`{"a":1}`))
+	assert.Error(t, err)
+}
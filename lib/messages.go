@@ -3,8 +3,6 @@ package lib
 import (
 	"context"
 	"net/http"
-	"net/url"
-	"strconv"
 )
 
 type MessagesService service
@@ -39,25 +37,6 @@ func (e *MessagesService) DeleteMessage(ctx context.Context, messageId string) (
 }
 
 func (q MessagesQueryParams) BuildQuery() string {
-	params := url.Values{}
-	if q.Channel != "" {
-		params.Add("channel", q.Channel)
-	}
-	if q.SubscriberId != "" {
-		params.Add("subscriberId", q.SubscriberId)
-	}
-	if q.TransactionId != nil && len(q.TransactionId) > 0 {
-		for _, s := range q.TransactionId {
-			params.Add("transactionId", s)
-		}
-	}
-	if q.Page != 0 {
-		i := strconv.Itoa(q.Page)
-		params.Add("page", i)
-	}
-	if q.Limit != 0 {
-		i := strconv.Itoa(q.Limit)
-		params.Add("limit", i)
-	}
+	params, _ := EncodeQuery(q)
 	return params.Encode()
 }
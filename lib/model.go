@@ -26,16 +26,29 @@ const (
 	PUSH  ChannelType = "push"
 )
 
+// Chat providers accepting subscriber credentials.
 const (
-	slack       ProviderIdType = "slack"
-	discord     ProviderIdType = "discord"
-	msteams     ProviderIdType = "msteams"
-	mattermost  ProviderIdType = "mattermost"
-	fcm         ProviderIdType = "fcm"
-	apns        ProviderIdType = "apns"
-	expo        ProviderIdType = "expo"
-	oneSignal   ProviderIdType = "one-signal"
-	pushWebhook ProviderIdType = "push-webhook"
+	ProviderSlack            ProviderIdType = "slack"
+	ProviderDiscord          ProviderIdType = "discord"
+	ProviderMSTeams          ProviderIdType = "msteams"
+	ProviderMattermost       ProviderIdType = "mattermost"
+	ProviderRyver            ProviderIdType = "ryver"
+	ProviderZulip            ProviderIdType = "zulip"
+	ProviderGrafanaOnCall    ProviderIdType = "grafana-on-call"
+	ProviderGetStream        ProviderIdType = "getstream"
+	ProviderRocketChat       ProviderIdType = "rocket-chat"
+	ProviderWhatsAppBusiness ProviderIdType = "whatsapp-business"
+)
+
+// Push providers accepting subscriber credentials.
+const (
+	ProviderFCM         ProviderIdType = "fcm"
+	ProviderAPNS        ProviderIdType = "apns"
+	ProviderExpo        ProviderIdType = "expo"
+	ProviderOneSignal   ProviderIdType = "one-signal"
+	ProviderPushpad     ProviderIdType = "pushpad"
+	ProviderPushWebhook ProviderIdType = "push-webhook"
+	ProviderPusherBeams ProviderIdType = "pusher-beams"
 )
 
 type Data struct {
@@ -68,6 +81,27 @@ type TriggerTopicRecipientsTypeSingle struct {
 	Type     string `json:"type,omitempty"`
 }
 
+// MultiTopicTriggerOptions describes a trigger sent to the union of the
+// subscribers of several topics. The To field of Trigger is ignored.
+type MultiTopicTriggerOptions struct {
+	TopicKeys []string
+	// Exclude lists subscriber ids that must not receive the event.
+	Exclude []string
+	// ExcludeActor removes the subscriber set in Trigger.Actor from the recipients.
+	ExcludeActor bool
+	Trigger      ITriggerPayloadOptions
+}
+
+// MultiTopicTriggerReport tells who received a multi-topic trigger and why.
+type MultiTopicTriggerReport struct {
+	// Recipients maps every subscriber id the event was sent to onto the keys
+	// of the topics it was found in.
+	Recipients map[string][]string
+	// Excluded lists the subscriber ids found in the topics but left out.
+	Excluded []string
+	Response EventResponse
+}
+
 type SubscriberPayload struct {
 	FirstName    string                 `json:"firstName,omitempty"`
 	LastName     string                 `json:"lastName,omitempty"`
@@ -79,6 +113,30 @@ type SubscriberPayload struct {
 	SubscriberId string                 `json:"subscriberId"`
 }
 
+type ListSubscribersOptions struct {
+	Page  *int `json:"page,omitempty" queryKey:"page"`
+	Limit *int `json:"limit,omitempty" queryKey:"limit"`
+	// Email and Phone filter the list on servers supporting subscriber search.
+	// Older servers ignore them, see SubscriberService.Find.
+	Email string `json:"email,omitempty" queryKey:"email,omitempty"`
+	Phone string `json:"phone,omitempty" queryKey:"phone,omitempty"`
+}
+
+type ListSubscribersResponse struct {
+	Page       int          `json:"page"`
+	PageSize   int          `json:"pageSize"`
+	TotalCount int          `json:"totalCount"`
+	HasMore    bool         `json:"hasMore"`
+	Data       []Subscriber `json:"data"`
+}
+
+// SubscriberLookup identifies a subscriber by email or phone. When both are
+// set a subscriber must match both.
+type SubscriberLookup struct {
+	Email string
+	Phone string
+}
+
 type SubscriberBulkPayload struct {
 	Subscribers []SubscriberPayload `json:"subscribers"`
 }
@@ -101,8 +159,8 @@ type JsonResponse struct {
 	Data interface{} `json:"data"`
 }
 type ExecutionsQueryParams struct {
-	NotificationId string
-	SubscriberId   string
+	NotificationId string `queryKey:"notificationId,omitempty"`
+	SubscriberId   string `queryKey:"subscriberId,omitempty"`
 }
 
 type EventResponse struct {
@@ -120,11 +178,11 @@ type EventRequest struct {
 }
 
 type MessagesQueryParams struct {
-	Channel       string
-	SubscriberId  string
-	TransactionId []string
-	Page          int
-	Limit         int
+	Channel       string   `queryKey:"channel,omitempty"`
+	SubscriberId  string   `queryKey:"subscriberId,omitempty"`
+	TransactionId []string `queryKey:"transactionId"`
+	Page          int      `queryKey:"page,omitempty"`
+	Limit         int      `queryKey:"limit,omitempty"`
 }
 
 // QueryBuilder gives us an interface to pass as arg to our API methods.
@@ -133,8 +191,41 @@ type QueryBuilder interface {
 	BuildQuery() string
 }
 
+// Subscriber is a subscriber as returned by the subscribers endpoints.
+type Subscriber struct {
+	ID             string                 `json:"_id"`
+	OrganizationID string                 `json:"_organizationId"`
+	EnvironmentID  string                 `json:"_environmentId"`
+	SubscriberId   string                 `json:"subscriberId"`
+	FirstName      string                 `json:"firstName,omitempty"`
+	LastName       string                 `json:"lastName,omitempty"`
+	Email          string                 `json:"email,omitempty"`
+	Phone          string                 `json:"phone,omitempty"`
+	Avatar         string                 `json:"avatar,omitempty"`
+	Locale         string                 `json:"locale,omitempty"`
+	Data           map[string]interface{} `json:"data,omitempty"`
+	Channels       []SubscriberChannel    `json:"channels,omitempty"`
+	IsOnline       bool                   `json:"isOnline"`
+	LastOnlineAt   *time.Time             `json:"lastOnlineAt,omitempty"`
+	Deleted        bool                   `json:"deleted"`
+	CreatedAt      time.Time              `json:"createdAt"`
+	UpdatedAt      time.Time              `json:"updatedAt"`
+}
+
+// SubscriberChannel holds the credentials of a subscriber for one chat or push
+// provider.
+type SubscriberChannel struct {
+	ProviderId            ProviderIdType `json:"providerId"`
+	IntegrationIdentifier string         `json:"integrationIdentifier,omitempty"`
+	Credentials           Credentials    `json:"credentials"`
+}
+
 type SubscriberResponse struct {
-	JsonResponse
+	Data Subscriber `json:"data"`
+}
+
+type DeleteSubscriberResponse struct {
+	Data Data `json:"data"`
 }
 
 type SubscriberBulkCreateResponse struct {
@@ -145,10 +236,15 @@ type SubscriberBulkCreateResponse struct {
 		Created []struct {
 			SubscriberId string `json:"subscriberId"`
 		} `json:"created"`
-		Failed []interface{} `json:"failed"`
+		Failed []SubscriberBulkFailure `json:"failed"`
 	} `json:"data"`
 }
 
+type SubscriberBulkFailure struct {
+	SubscriberId string `json:"subscriberId"`
+	Message      string `json:"message"`
+}
+
 type Template struct {
 	ID       string `json:"_id"`
 	Name     string `json:"name"`
@@ -168,18 +264,17 @@ type Channel struct {
 	Push  *bool `json:"push,omitempty"`
 }
 
+type SubscriberPreference struct {
+	Template   Template   `json:"template"`
+	Preference Preference `json:"preference"`
+}
+
 type SubscriberPreferencesResponse struct {
-	Data []struct {
-		Template   Template   `json:"template"`
-		Preference Preference `json:"preference"`
-	} `json:"data"`
+	Data []SubscriberPreference `json:"data"`
 }
 
 type UpdateSubscriberPreferencesResponse struct {
-	Data struct {
-		Template   Template   `json:"template"`
-		Preference Preference `json:"preference"`
-	} `json:"data"`
+	Data SubscriberPreference `json:"data"`
 }
 
 type UpdateSubscriberPreferencesChannel struct {
@@ -282,9 +377,9 @@ type CheckTopicSubscriberResponse struct {
 }
 
 type ListTopicsOptions struct {
-	Page     *int    `json:"page,omitempty"`
-	PageSize *int    `json:"pageSize,omitempty"`
-	Key      *string `json:"key,omitempty"`
+	Page     *int    `json:"page,omitempty" queryKey:"page"`
+	PageSize *int    `json:"pageSize,omitempty" queryKey:"pageSize"`
+	Key      *string `json:"key,omitempty" queryKey:"key"`
 }
 
 type CreateTopicRequest struct {
@@ -301,17 +396,26 @@ type SubscribersTopicRequest struct {
 }
 
 type SubscriberNotificationFeedOptions struct {
-	Page           int         `queryKey:"page"`
-	FeedIdentifier string      `queryKey:"feedIdentifier"`
-	Seen           bool        `queryKey:"seen"`
-	Payload        interface{} `queryKey:"payload"`
-}
+	Page           int    `queryKey:"page,omitempty"`
+	Limit          int    `queryKey:"limit,omitempty"`
+	FeedIdentifier string `queryKey:"feedIdentifier,omitempty"`
+	// Seen and Read filter the feed when set.
+	Seen *bool `queryKey:"seen"`
+	Read *bool `queryKey:"read"`
+	// Payload filters the feed on the trigger payload.
+	Payload interface{} `queryKey:"payload,base64"`
+}
+
+// Base64Payload holds a notification feed payload filter encoded by hand.
+//
+// Deprecated: EncodeQuery base64 encodes SubscriberNotificationFeedOptions.Payload,
+// set it to the payload itself.
 type Base64Payload struct {
 	Payload string `queryKey:"payload"`
 }
 
 type SubscriberUnseenCountOptions struct {
-	Seen *bool `json:"seen"`
+	Seen *bool `json:"seen" queryKey:"seen"`
 }
 
 type SubscriberMarkMessageSeenOptions struct {
@@ -320,42 +424,107 @@ type SubscriberMarkMessageSeenOptions struct {
 	Read      bool   `json:"read"`
 }
 
+type MarkAs string
+
+const (
+	MarkAsRead   MarkAs = "read"
+	MarkAsSeen   MarkAs = "seen"
+	MarkAsUnread MarkAs = "unread"
+	MarkAsUnseen MarkAs = "unseen"
+)
+
+type MarkAllMessagesOptions struct {
+	MarkAs         MarkAs `json:"markAs"`
+	FeedIdentifier string `json:"feedIdentifier,omitempty"`
+}
+
+type MarkAllMessagesResponse struct {
+	Data int `json:"data"`
+}
+
+// MessageMark holds the flags to set on messages. Nil flags are left as they
+// are.
+type MessageMark struct {
+	Seen *bool `json:"seen,omitempty"`
+	Read *bool `json:"read,omitempty"`
+}
+
+type SubscriberMarkMessagesOptions struct {
+	MessageIDs []string    `json:"messageId"`
+	Mark       MessageMark `json:"mark"`
+}
+
+type MarkMessagesResponse struct {
+	Data []NotificationFeedData `json:"data"`
+}
+
 type NotificationFeedData struct {
-	CTA              CTA       `json:"cta"`
-	Channel          string    `json:"channel"`
-	Content          string    `json:"content"`
-	CreatedAt        time.Time `json:"createdAt"`
-	Deleted          bool      `json:"deleted"`
-	DeviceTokens     []string  `json:"deviceTokens"`
-	DirectWebhookURL string    `json:"directWebhookUrl"`
-	EnvironmentID    string    `json:"_environmentId"`
-	ErrorID          string    `json:"errorId"`
-	ErrorText        string    `json:"errorText"`
-	FeedID           string    `json:"_feedId"`
-	ID               string    `json:"_id"`
-	JobID            string    `json:"_jobId"`
-	LastReadDate     time.Time `json:"lastReadDate"`
-	LastSeenDate     time.Time `json:"lastSeenDate"`
-	MessageTemplate  string    `json:"_messageTemplateId"`
-	NotificationID   string    `json:"_notificationId"`
-	OrganizationID   string    `json:"_organizationId"`
-	Payload          struct {
-		UpdateMessage string `json:"updateMessage"`
-	} `json:"payload"`
-	ProviderID string `json:"providerId"`
-	Read       bool   `json:"read"`
-	ResponseID string `json:"id"`
-	Seen       bool   `json:"seen"`
-	Status     string `json:"status"`
-	Subscriber struct {
+	CTA              CTA                    `json:"cta"`
+	Channel          string                 `json:"channel"`
+	Content          string                 `json:"content"`
+	CreatedAt        time.Time              `json:"createdAt"`
+	Deleted          bool                   `json:"deleted"`
+	DeviceTokens     []string               `json:"deviceTokens"`
+	DirectWebhookURL string                 `json:"directWebhookUrl"`
+	EnvironmentID    string                 `json:"_environmentId"`
+	ErrorID          string                 `json:"errorId"`
+	ErrorText        string                 `json:"errorText"`
+	FeedID           string                 `json:"_feedId"`
+	ID               string                 `json:"_id"`
+	JobID            string                 `json:"_jobId"`
+	LastReadDate     time.Time              `json:"lastReadDate"`
+	LastSeenDate     time.Time              `json:"lastSeenDate"`
+	MessageTemplate  string                 `json:"_messageTemplateId"`
+	NotificationID   string                 `json:"_notificationId"`
+	OrganizationID   string                 `json:"_organizationId"`
+	Payload          MessagePayload         `json:"payload"`
+	Overrides        map[string]interface{} `json:"overrides,omitempty"`
+	ProviderID       string                 `json:"providerId"`
+	Read             bool                   `json:"read"`
+	ResponseID       string                 `json:"id"`
+	Seen             bool                   `json:"seen"`
+	Status           string                 `json:"status"`
+	Subscriber       struct {
 		ID           string `json:"_id"`
 		SubscriberID string `json:"subscriberId"`
 	} `json:"subscriber"`
-	SubscriberID       string    `json:"_subscriberId"`
-	TemplateID         string    `json:"_templateId"`
-	TemplateIdentifier string    `json:"templateIdentifier"`
-	TransactionID      string    `json:"transactionId"`
-	UpdatedAt          time.Time `json:"updatedAt"`
+	SubscriberID       string           `json:"_subscriberId"`
+	Actor              *MessageActor    `json:"actor,omitempty"`
+	ActorID            string           `json:"_actorId,omitempty"`
+	ActorSubscriber    *ActorSubscriber `json:"actorSubscriber,omitempty"`
+	Avatar             string           `json:"avatar,omitempty"`
+	Subject            string           `json:"subject,omitempty"`
+	Identifier         string           `json:"identifier,omitempty"`
+	LayoutID           string           `json:"_layoutId,omitempty"`
+	TemplateID         string           `json:"_templateId"`
+	TemplateIdentifier string           `json:"templateIdentifier"`
+	TransactionID      string           `json:"transactionId"`
+	UpdatedAt          time.Time        `json:"updatedAt"`
+}
+
+type ActorType string
+
+const (
+	ActorNone         ActorType = "none"
+	ActorUser         ActorType = "user"
+	ActorSystemIcon   ActorType = "system_icon"
+	ActorSystemCustom ActorType = "system_custom"
+)
+
+// MessageActor is the avatar shown next to an in-app message. Data holds the
+// icon name or image URL for system actors.
+type MessageActor struct {
+	Type ActorType `json:"type"`
+	Data string    `json:"data,omitempty"`
+}
+
+// ActorSubscriber is the subscriber who triggered a message with a user actor.
+type ActorSubscriber struct {
+	ID           string `json:"_id"`
+	SubscriberID string `json:"subscriberId"`
+	FirstName    string `json:"firstName,omitempty"`
+	LastName     string `json:"lastName,omitempty"`
+	Avatar       string `json:"avatar,omitempty"`
 }
 
 type SubscriberNotificationFeedResponse struct {
@@ -375,6 +544,15 @@ type Credentials struct {
 	WebhookUrl   string   `json:"webhookUrl,omitempty"`
 	Channel      string   `json:"channel,omitempty"`
 	DeviceTokens []string `json:"deviceTokens,omitempty"`
+	AlertUid     string   `json:"alertUid,omitempty"`
+	Title        string   `json:"title,omitempty"`
+	ImageUrl     string   `json:"imageUrl,omitempty"`
+	State        string   `json:"state,omitempty"`
+	ExternalUrl  string   `json:"externalUrl,omitempty"`
+}
+
+type UpdateOnlineStatusPayload struct {
+	IsOnline bool `json:"isOnline"`
 }
 
 type SubscriberCredentialPayload struct {
@@ -383,21 +561,53 @@ type SubscriberCredentialPayload struct {
 	ProviderId            ProviderIdType `json:"providerId"`
 }
 
+type ButtonType string
+
+const (
+	ButtonPrimary   ButtonType = "primary"
+	ButtonSecondary ButtonType = "secondary"
+)
+
+type MessageActionStatus string
+
+const (
+	MessageActionPending MessageActionStatus = "pending"
+	MessageActionDone    MessageActionStatus = "done"
+)
+
+type CTAButton struct {
+	Type          ButtonType `json:"type"`
+	Content       string     `json:"content"`
+	ResultContent string     `json:"resultContent"`
+}
+
+type CTAResult struct {
+	Payload map[string]interface{} `json:"payload"`
+	Type    ButtonType             `json:"type"`
+}
+
+type CTAAction struct {
+	Status  MessageActionStatus `json:"status"`
+	Buttons []CTAButton         `json:"buttons"`
+	Result  CTAResult           `json:"result"`
+}
+
 type CTA struct {
-	Type   string `json:"type"`
-	Action struct {
-		Status  string `json:"status"`
-		Buttons []struct {
-			Type          string `json:"type"`
-			Content       string `json:"content"`
-			ResultContent string `json:"resultContent"`
-		} `json:"buttons"`
-		Result struct {
-			Payload map[string]interface{} `json:"payload"`
-			Type    string                 `json:"type"`
-		} `json:"result"`
-	}
+	Type   string    `json:"type"`
+	Action CTAAction `json:"action"`
 }
+
+// MessageActionOptions records the click on a CTA button. Payload is stored
+// as the action result.
+type MessageActionOptions struct {
+	Status  MessageActionStatus    `json:"status"`
+	Payload map[string]interface{} `json:"payload,omitempty"`
+}
+
+type MessageActionResponse struct {
+	Data NotificationFeedData `json:"data"`
+}
+
 type IntegrationCredentials struct {
 	ApiKey           string                 `json:"apiKey,omitempty"`
 	User             string                 `json:"user,omitempty"`
@@ -488,12 +698,23 @@ type BulkTriggerOptions struct {
 	Overrides     interface{} `json:"overrides,omitempty"`
 	TransactionId string      `json:"transactionId,omitempty"`
 	Actor         interface{} `json:"actor,omitempty"`
+	Tenant        interface{} `json:"tenant,omitempty"`
 }
 
 type BulkTriggerEvent struct {
 	Events []BulkTriggerOptions `json:"events"`
 }
 
+// FanOutTriggerData is the aggregated EventResponse data returned by Trigger
+// when the recipient list had to be split into several triggers.
+type FanOutTriggerData struct {
+	Acknowledged   bool          `json:"acknowledged"`
+	Status         string        `json:"status"`
+	TransactionId  string        `json:"transactionId"`
+	TransactionIds []string      `json:"transactionIds"`
+	Responses      []interface{} `json:"responses"`
+}
+
 type BroadcastEventToAll struct {
 	Name          interface{} `json:"name,omitempty"`
 	Payload       interface{} `json:"payload,omitempty"`
@@ -523,10 +744,10 @@ type CreateLayoutResponse struct {
 	} `json:"data"`
 }
 type LayoutRequestOptions struct {
-	Page     *int    `json:"page,omitempty"`
-	PageSize *int    `json:"pageSize,omitempty"`
-	Key      *string `json:"key,omitempty"`
-	OrderBy  *int    `json:"orderBy,omitempty"`
+	Page     *int    `json:"page,omitempty" queryKey:"page"`
+	PageSize *int    `json:"pageSize,omitempty" queryKey:"pageSize"`
+	Key      *string `json:"key,omitempty" queryKey:"key"`
+	OrderBy  *int    `json:"orderBy,omitempty" queryKey:"orderBy"`
 }
 type LayoutResponse struct {
 	Id             string        `json:"_id"`
@@ -584,9 +805,9 @@ type BlueprintGroupByCategoryResponse struct {
 }
 
 type ChangesGetQuery struct {
-	Page     int    `json:"page,omitempty"`
-	Limit    int    `json:"limit,omitempty"`
-	Promoted string `json:"promoted,omitempty"`
+	Page     int    `json:"page,omitempty" queryKey:"page"`
+	Limit    int    `json:"limit,omitempty" queryKey:"limit"`
+	Promoted string `json:"promoted,omitempty" queryKey:"promoted"`
 }
 
 type ChangesGetResponseData struct {
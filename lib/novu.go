@@ -32,6 +32,14 @@ type Config struct {
 	BackendURL  *url.URL
 	HttpClient  *http.Client
 	RetryConfig *RetryConfigType
+
+	// MaxTriggerRecipients caps the number of subscribers sent in a single
+	// trigger. Larger recipient lists are split, see EventService.Trigger.
+	// Defaults to DefaultMaxTriggerRecipients when zero.
+	MaxTriggerRecipients int
+
+	// ScrubPolicy, when set, is applied to every trigger sent through EventApi.
+	ScrubPolicy *ScrubPolicy
 }
 
 type APIClient struct {
@@ -101,6 +109,7 @@ func NewAPIClient(apiKey string, cfg *Config) *APIClient {
 	c.common.client = c
 
 	// API Services
+	c.BlueprintApi = (*BlueprintService)(&c.common)
 	c.ChangesApi = (*ChangesService)(&c.common)
 	c.EventApi = (*EventService)(&c.common)
 	c.ExecutionsApi = (*ExecutionsService)(&c.common)
@@ -117,8 +126,14 @@ func NewAPIClient(apiKey string, cfg *Config) *APIClient {
 }
 
 func (c APIClient) sendRequest(req *http.Request, resp interface{}) (*http.Response, error) {
-	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", fmt.Sprintf("ApiKey %s", c.apiKey))
+
+	return c.doRequest(req, resp)
+}
+
+// doRequest sends a request that already carries its Authorization header.
+func (c APIClient) doRequest(req *http.Request, resp interface{}) (*http.Response, error) {
+	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Idempotency-Key", uuid.New().String())
 
 	res, err := c.config.HttpClient.Do(req)
@@ -149,6 +164,23 @@ func (c APIClient) sendRequest(req *http.Request, resp interface{}) (*http.Respo
 	return res, nil
 }
 
+func (c APIClient) maxTriggerRecipients() int {
+	if c.config.MaxTriggerRecipients > 0 {
+		return c.config.MaxTriggerRecipients
+	}
+
+	return DefaultMaxTriggerRecipients
+}
+
+func (c APIClient) scrub(data *ITriggerPayloadOptions) error {
+	if c.config.ScrubPolicy == nil {
+		return nil
+	}
+
+	_, err := c.config.ScrubPolicy.Apply(data)
+	return err
+}
+
 func (c APIClient) mergeStruct(target, patch interface{}) (interface{}, error) {
 	var m map[string]interface{}
 
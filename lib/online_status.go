@@ -0,0 +1,230 @@
+package lib
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultOnlineStatusDelay   = 2 * time.Second
+	DefaultOnlineStatusSentTTL = 10 * time.Minute
+)
+
+// ErrOnlineStatusBatcherClosed is returned by Set once the batcher is closed.
+var ErrOnlineStatusBatcherClosed = errors.New("online status batcher is closed")
+
+// OnlineStatusBatcherOptions configures an OnlineStatusBatcher.
+type OnlineStatusBatcherOptions struct {
+	// Delay is how long a subscriber status must stay unchanged before it is
+	// sent. Defaults to DefaultOnlineStatusDelay.
+	Delay time.Duration
+
+	// Timeout bounds every UpdateOnlineStatus call made in the background. Zero
+	// means no timeout.
+	Timeout time.Duration
+
+	// SentTTL is how long the last status sent for a subscriber is remembered
+	// to skip sending it again. Defaults to DefaultOnlineStatusSentTTL.
+	SentTTL time.Duration
+
+	// OnError, when set, is called when a background update fails.
+	OnError func(subscriberID string, online bool, err error)
+}
+
+type onlineStatusEntry struct {
+	online bool
+	timer  *time.Timer
+}
+
+// onlineStatusState tracks what was sent for a subscriber. inFlight is set
+// while a send runs and closed when it ends; desired is the latest status
+// handed to send, which the running send picks up before it ends.
+type onlineStatusState struct {
+	desired  bool
+	sent     bool
+	hasSent  bool
+	inFlight chan struct{}
+	evict    *time.Timer
+}
+
+// OnlineStatusBatcher coalesces the connect and disconnect events of
+// subscribers: a status is only sent once it stayed unchanged for the
+// configured delay, and only when it differs from the last status sent.
+// Updates of a subscriber are sent one at a time, so they reach Novu in
+// order.
+type OnlineStatusBatcher struct {
+	client *APIClient
+	opts   OnlineStatusBatcherOptions
+
+	mu      sync.Mutex
+	pending map[string]*onlineStatusEntry
+	states  map[string]*onlineStatusState
+	closed  bool
+}
+
+func NewOnlineStatusBatcher(client *APIClient, opts OnlineStatusBatcherOptions) *OnlineStatusBatcher {
+	if opts.Delay <= 0 {
+		opts.Delay = DefaultOnlineStatusDelay
+	}
+	if opts.SentTTL <= 0 {
+		opts.SentTTL = DefaultOnlineStatusSentTTL
+	}
+
+	return &OnlineStatusBatcher{
+		client:  client,
+		opts:    opts,
+		pending: make(map[string]*onlineStatusEntry),
+		states:  make(map[string]*onlineStatusState),
+	}
+}
+
+// Set records the status of a subscriber, e.g. from a websocket connect or
+// disconnect.
+func (b *OnlineStatusBatcher) Set(subscriberID string, online bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return ErrOnlineStatusBatcherClosed
+	}
+
+	if entry, ok := b.pending[subscriberID]; ok {
+		entry.online = online
+		entry.timer.Reset(b.opts.Delay)
+		return nil
+	}
+
+	entry := &onlineStatusEntry{online: online}
+	entry.timer = time.AfterFunc(b.opts.Delay, func() {
+		b.fire(subscriberID, entry)
+	})
+	b.pending[subscriberID] = entry
+
+	return nil
+}
+
+// Pending returns the number of subscribers whose status is not sent yet.
+func (b *OnlineStatusBatcher) Pending() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.pending)
+}
+
+// Flush sends every pending status right away.
+func (b *OnlineStatusBatcher) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = make(map[string]*onlineStatusEntry)
+	for _, entry := range pending {
+		entry.timer.Stop()
+	}
+	b.mu.Unlock()
+
+	var firstErr error
+	for subscriberID, entry := range pending {
+		if err := b.send(ctx, subscriberID, entry.online); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// Close flushes the pending statuses and rejects further updates.
+func (b *OnlineStatusBatcher) Close(ctx context.Context) error {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+
+	return b.Flush(ctx)
+}
+
+func (b *OnlineStatusBatcher) fire(subscriberID string, entry *onlineStatusEntry) {
+	b.mu.Lock()
+	if b.pending[subscriberID] != entry {
+		// Flushed in the meantime.
+		b.mu.Unlock()
+		return
+	}
+	delete(b.pending, subscriberID)
+	online := entry.online
+	b.mu.Unlock()
+
+	ctx := context.Background()
+	if b.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.opts.Timeout)
+		defer cancel()
+	}
+
+	if err := b.send(ctx, subscriberID, online); err != nil && b.opts.OnError != nil {
+		b.opts.OnError(subscriberID, online, err)
+	}
+}
+
+// send makes the status of the subscriber in Novu the given one. When a send
+// for the subscriber is already running, it waits for it to end: the running
+// send sends the latest status before it ends.
+func (b *OnlineStatusBatcher) send(ctx context.Context, subscriberID string, online bool) error {
+	b.mu.Lock()
+	state := b.states[subscriberID]
+	if state == nil {
+		state = &onlineStatusState{}
+		b.states[subscriberID] = state
+	}
+	state.desired = online
+
+	for state.inFlight != nil {
+		done := state.inFlight
+		b.mu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		b.mu.Lock()
+
+		// An idle state can be evicted, and replaced by a newer one, while
+		// waiting.
+		if current := b.states[subscriberID]; current == nil {
+			b.states[subscriberID] = state
+		} else {
+			state = current
+		}
+	}
+
+	state.inFlight = make(chan struct{})
+	if state.evict != nil {
+		state.evict.Stop()
+	}
+
+	var err error
+	for !state.hasSent || state.sent != state.desired {
+		want := state.desired
+		b.mu.Unlock()
+		_, err = b.client.SubscriberApi.UpdateOnlineStatus(ctx, subscriberID, want)
+		b.mu.Lock()
+		if err != nil {
+			err = errors.Wrapf(err, "unable to update online status of %s", subscriberID)
+			break
+		}
+		state.sent, state.hasSent = want, true
+	}
+
+	close(state.inFlight)
+	state.inFlight = nil
+	state.evict = time.AfterFunc(b.opts.SentTTL, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.states[subscriberID] == state && state.inFlight == nil {
+			delete(b.states, subscriberID)
+		}
+	})
+	b.mu.Unlock()
+
+	return err
+}
@@ -0,0 +1,146 @@
+package lib_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/saeid-a/go-novu/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type onlineStatusUpdate struct {
+	SubscriberId string
+	Online       bool
+}
+
+func onlineStatusServer(t *testing.T) (*lib.APIClient, func() []onlineStatusUpdate) {
+	var mu sync.Mutex
+	var updates []onlineStatusUpdate
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodPatch, req.Method)
+
+		var body lib.UpdateOnlineStatusPayload
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		id := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, "/v1/subscribers/"), "/online-status")
+
+		mu.Lock()
+		updates = append(updates, onlineStatusUpdate{id, body.IsOnline})
+		mu.Unlock()
+
+		w.Write([]byte(`{"data": {}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(server.URL)})
+	return c, func() []onlineStatusUpdate {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]onlineStatusUpdate(nil), updates...)
+	}
+}
+
+func TestOnlineStatusBatcher_CoalescesFlaps(t *testing.T) {
+	c, updates := onlineStatusServer(t)
+	batcher := lib.NewOnlineStatusBatcher(c, lib.OnlineStatusBatcherOptions{Delay: time.Hour})
+
+	require.NoError(t, batcher.Set("alice", true))
+	require.NoError(t, batcher.Set("alice", false))
+	require.NoError(t, batcher.Set("alice", true))
+	assert.Equal(t, 1, batcher.Pending())
+
+	require.NoError(t, batcher.Flush(context.Background()))
+	assert.Equal(t, []onlineStatusUpdate{{"alice", true}}, updates())
+
+	// A flap ending on the status already sent makes no call.
+	require.NoError(t, batcher.Set("alice", false))
+	require.NoError(t, batcher.Set("alice", true))
+	require.NoError(t, batcher.Close(context.Background()))
+	assert.Len(t, updates(), 1)
+
+	assert.ErrorIs(t, batcher.Set("alice", false), lib.ErrOnlineStatusBatcherClosed)
+}
+
+func TestOnlineStatusBatcher_SendsAfterDelay(t *testing.T) {
+	c, updates := onlineStatusServer(t)
+	batcher := lib.NewOnlineStatusBatcher(c, lib.OnlineStatusBatcherOptions{Delay: 10 * time.Millisecond})
+
+	require.NoError(t, batcher.Set("bob", true))
+	require.NoError(t, batcher.Set("carol", false))
+
+	require.Eventually(t, func() bool { return len(updates()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.ElementsMatch(t, []onlineStatusUpdate{{"bob", true}, {"carol", false}}, updates())
+	assert.Zero(t, batcher.Pending())
+}
+
+func TestOnlineStatusBatcher_SerializesSends(t *testing.T) {
+	var mu sync.Mutex
+	var updates []bool
+	received := make(chan struct{}, 2)
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body lib.UpdateOnlineStatusPayload
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		received <- struct{}{}
+		<-release
+
+		mu.Lock()
+		updates = append(updates, body.IsOnline)
+		mu.Unlock()
+		w.Write([]byte(`{"data": {}}`))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(server.URL)})
+	batcher := lib.NewOnlineStatusBatcher(c, lib.OnlineStatusBatcherOptions{Delay: time.Hour})
+	ctx := context.Background()
+
+	flushed := make(chan error, 2)
+	require.NoError(t, batcher.Set("alice", true))
+	go func() { flushed <- batcher.Flush(ctx) }()
+	<-received
+
+	// The disconnect arrives while the connect is still being sent.
+	require.NoError(t, batcher.Set("alice", false))
+	go func() { flushed <- batcher.Flush(ctx) }()
+
+	select {
+	case <-received:
+		require.FailNow(t, "a second update was sent while the first was in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release <- struct{}{}
+	<-received
+	release <- struct{}{}
+	require.NoError(t, <-flushed)
+	require.NoError(t, <-flushed)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []bool{true, false}, updates)
+}
+
+func TestOnlineStatusBatcher_ForgetsSentStatus(t *testing.T) {
+	c, updates := onlineStatusServer(t)
+	batcher := lib.NewOnlineStatusBatcher(c, lib.OnlineStatusBatcherOptions{Delay: time.Hour, SentTTL: 10 * time.Millisecond})
+	ctx := context.Background()
+
+	require.NoError(t, batcher.Set("alice", true))
+	require.NoError(t, batcher.Flush(ctx))
+
+	// Once forgotten, the same status is sent again.
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(t, batcher.Set("alice", true))
+	require.NoError(t, batcher.Flush(ctx))
+	assert.Equal(t, []onlineStatusUpdate{{"alice", true}, {"alice", true}}, updates())
+}
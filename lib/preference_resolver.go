@@ -0,0 +1,147 @@
+package lib
+
+// PreferenceSource tells which setting decided the effective state of a
+// channel.
+type PreferenceSource string
+
+const (
+	// SourceCritical: the workflow is critical and ignores preferences.
+	SourceCritical PreferenceSource = "critical"
+	// SourceDefault: nothing is set, channels are enabled by default.
+	SourceDefault PreferenceSource = "default"
+	// SourceWorkflow: the workflow preferenceSettings.
+	SourceWorkflow PreferenceSource = "workflow"
+	// SourceGlobal: the global channel preference of the subscriber.
+	SourceGlobal PreferenceSource = "global"
+	// SourceGlobalDisabled: the subscriber turned off every notification.
+	SourceGlobalDisabled PreferenceSource = "global_disabled"
+	// SourceSubscriber: the workflow channel preference of the subscriber.
+	SourceSubscriber PreferenceSource = "subscriber"
+	// SourceSubscriberDisabled: the subscriber turned off the workflow.
+	SourceSubscriberDisabled PreferenceSource = "subscriber_disabled"
+)
+
+type EffectiveChannel struct {
+	Enabled bool             `json:"enabled"`
+	Source  PreferenceSource `json:"source"`
+}
+
+// EffectivePreferences are the channels a workflow will actually deliver on
+// for a subscriber.
+type EffectivePreferences struct {
+	WorkflowID string                           `json:"workflowId"`
+	Name       string                           `json:"name"`
+	Critical   bool                             `json:"critical"`
+	Channels   map[ChannelType]EffectiveChannel `json:"channels"`
+}
+
+// EnabledChannels lists the enabled channels in PreferenceChannels order.
+func (p EffectivePreferences) EnabledChannels() []ChannelType {
+	var channels []ChannelType
+	for _, channel := range PreferenceChannels {
+		if p.Channels[channel].Enabled {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
+// ResolvePreferences computes the effective preferences of a subscriber for
+// every workflow, without calling the API. global may be nil when the
+// subscriber has no global preferences, and subscriber holds the workflow
+// preferences as returned by GetPreferences.
+//
+// The precedence follows Novu, the last one set winning:
+//
+//  1. channels are enabled by default
+//  2. the workflow preferenceSettings
+//  3. the global channel preferences of the subscriber
+//  4. the workflow channel preferences of the subscriber
+//
+// A subscriber who disabled the workflow or all notifications receives
+// nothing, and critical workflows ignore every preference.
+func ResolvePreferences(workflows []GetWorkflowResponse, global *Preference, subscriber []SubscriberPreference) []EffectivePreferences {
+	byWorkflow := make(map[string]*Preference, len(subscriber))
+	for i := range subscriber {
+		byWorkflow[subscriber[i].Template.ID] = &subscriber[i].Preference
+	}
+
+	resolved := make([]EffectivePreferences, 0, len(workflows))
+	for _, workflow := range workflows {
+		resolved = append(resolved, ResolveWorkflowPreferences(workflow, global, byWorkflow[workflow.ID]))
+	}
+
+	return resolved
+}
+
+// ResolveWorkflowPreferences computes the effective preferences of a single
+// workflow. See ResolvePreferences for the precedence rules.
+func ResolveWorkflowPreferences(workflow GetWorkflowResponse, global *Preference, subscriber *Preference) EffectivePreferences {
+	effective := EffectivePreferences{
+		WorkflowID: workflow.ID,
+		Name:       workflow.Name,
+		Critical:   workflow.Critical,
+		Channels:   make(map[ChannelType]EffectiveChannel),
+	}
+
+	for _, channel := range workflowChannels(workflow) {
+		if workflow.Critical {
+			effective.Channels[channel] = EffectiveChannel{Enabled: true, Source: SourceCritical}
+			continue
+		}
+
+		state := EffectiveChannel{Enabled: true, Source: SourceDefault}
+		if enabled := workflow.PreferenceSettings.Get(channel); enabled != nil {
+			state = EffectiveChannel{Enabled: *enabled, Source: SourceWorkflow}
+		}
+		if global != nil {
+			if enabled := global.Channels.Get(channel); enabled != nil {
+				state = EffectiveChannel{Enabled: *enabled, Source: SourceGlobal}
+			}
+		}
+		if subscriber != nil {
+			if enabled := subscriber.Channels.Get(channel); enabled != nil {
+				state = EffectiveChannel{Enabled: *enabled, Source: SourceSubscriber}
+			}
+		}
+
+		switch {
+		case global != nil && !global.Enabled:
+			state = EffectiveChannel{Enabled: false, Source: SourceGlobalDisabled}
+		case subscriber != nil && !subscriber.Enabled:
+			state = EffectiveChannel{Enabled: false, Source: SourceSubscriberDisabled}
+		}
+
+		effective.Channels[channel] = state
+	}
+
+	return effective
+}
+
+// workflowChannels returns the channels the workflow has steps for, or every
+// preference channel when its steps are not known.
+func workflowChannels(workflow GetWorkflowResponse) []ChannelType {
+	present := make(map[ChannelType]bool)
+	for _, step := range workflow.Steps {
+		s, ok := step.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		template, ok := s["template"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if t, ok := template["type"].(string); ok {
+			present[ChannelType(t)] = true
+		}
+	}
+
+	var channels []ChannelType
+	for _, channel := range PreferenceChannels {
+		if len(present) == 0 || present[channel] {
+			channels = append(channels, channel)
+		}
+	}
+
+	return channels
+}
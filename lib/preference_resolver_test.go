@@ -0,0 +1,122 @@
+package lib_test
+
+import (
+	"testing"
+
+	"github.com/saeid-a/go-novu/lib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveWorkflowPreferences(t *testing.T) {
+	on, off := true, false
+	enabled := func(source lib.PreferenceSource) lib.EffectiveChannel {
+		return lib.EffectiveChannel{Enabled: true, Source: source}
+	}
+	disabled := func(source lib.PreferenceSource) lib.EffectiveChannel {
+		return lib.EffectiveChannel{Enabled: false, Source: source}
+	}
+	emailAndInApp := []interface{}{
+		map[string]interface{}{"template": map[string]interface{}{"type": "email"}},
+		map[string]interface{}{"template": map[string]interface{}{"type": "digest"}},
+		map[string]interface{}{"template": map[string]interface{}{"type": "in_app"}},
+	}
+
+	tests := map[string]struct {
+		workflow   lib.GetWorkflowResponse
+		global     *lib.Preference
+		subscriber *lib.Preference
+		want       map[lib.ChannelType]lib.EffectiveChannel
+	}{
+		"defaults enable every step channel": {
+			workflow: lib.GetWorkflowResponse{Steps: emailAndInApp},
+			want: map[lib.ChannelType]lib.EffectiveChannel{
+				lib.EMAIL: enabled(lib.SourceDefault),
+				lib.INAPP: enabled(lib.SourceDefault),
+			},
+		},
+		"workflow settings over defaults": {
+			workflow: lib.GetWorkflowResponse{Steps: emailAndInApp, PreferenceSettings: lib.Channel{Email: &off}},
+			want: map[lib.ChannelType]lib.EffectiveChannel{
+				lib.EMAIL: disabled(lib.SourceWorkflow),
+				lib.INAPP: enabled(lib.SourceDefault),
+			},
+		},
+		"global over workflow settings": {
+			workflow: lib.GetWorkflowResponse{Steps: emailAndInApp, PreferenceSettings: lib.Channel{Email: &off, InApp: &on}},
+			global:   &lib.Preference{Enabled: true, Channels: lib.Channel{Email: &on, InApp: &off}},
+			want: map[lib.ChannelType]lib.EffectiveChannel{
+				lib.EMAIL: enabled(lib.SourceGlobal),
+				lib.INAPP: disabled(lib.SourceGlobal),
+			},
+		},
+		"subscriber workflow over global": {
+			workflow:   lib.GetWorkflowResponse{Steps: emailAndInApp},
+			global:     &lib.Preference{Enabled: true, Channels: lib.Channel{Email: &off}},
+			subscriber: &lib.Preference{Enabled: true, Channels: lib.Channel{Email: &on}},
+			want: map[lib.ChannelType]lib.EffectiveChannel{
+				lib.EMAIL: enabled(lib.SourceSubscriber),
+				lib.INAPP: enabled(lib.SourceDefault),
+			},
+		},
+		"subscriber disabled workflow": {
+			workflow:   lib.GetWorkflowResponse{Steps: emailAndInApp},
+			subscriber: &lib.Preference{Enabled: false, Channels: lib.Channel{Email: &on}},
+			want: map[lib.ChannelType]lib.EffectiveChannel{
+				lib.EMAIL: disabled(lib.SourceSubscriberDisabled),
+				lib.INAPP: disabled(lib.SourceSubscriberDisabled),
+			},
+		},
+		"global disabled wins over subscriber workflow": {
+			workflow:   lib.GetWorkflowResponse{Steps: emailAndInApp},
+			global:     &lib.Preference{Enabled: false},
+			subscriber: &lib.Preference{Enabled: true, Channels: lib.Channel{Email: &on}},
+			want: map[lib.ChannelType]lib.EffectiveChannel{
+				lib.EMAIL: disabled(lib.SourceGlobalDisabled),
+				lib.INAPP: disabled(lib.SourceGlobalDisabled),
+			},
+		},
+		"critical ignores preferences": {
+			workflow:   lib.GetWorkflowResponse{Steps: emailAndInApp, Critical: true, PreferenceSettings: lib.Channel{Email: &off}},
+			global:     &lib.Preference{Enabled: false},
+			subscriber: &lib.Preference{Enabled: false},
+			want: map[lib.ChannelType]lib.EffectiveChannel{
+				lib.EMAIL: enabled(lib.SourceCritical),
+				lib.INAPP: enabled(lib.SourceCritical),
+			},
+		},
+		"unknown steps cover every channel": {
+			workflow: lib.GetWorkflowResponse{PreferenceSettings: lib.Channel{Sms: &off}},
+			want: map[lib.ChannelType]lib.EffectiveChannel{
+				lib.EMAIL: enabled(lib.SourceDefault),
+				lib.SMS:   disabled(lib.SourceWorkflow),
+				lib.CHAT:  enabled(lib.SourceDefault),
+				lib.INAPP: enabled(lib.SourceDefault),
+				lib.PUSH:  enabled(lib.SourceDefault),
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := lib.ResolveWorkflowPreferences(tc.workflow, tc.global, tc.subscriber)
+			assert.Equal(t, tc.want, got.Channels)
+		})
+	}
+}
+
+func TestResolvePreferences(t *testing.T) {
+	off := false
+	workflows := []lib.GetWorkflowResponse{
+		{ID: "digest", Name: "Digest"},
+		{ID: "security", Name: "Security", Critical: true},
+	}
+	subscriber := []lib.SubscriberPreference{
+		{Template: lib.Template{ID: "digest"}, Preference: lib.Preference{Enabled: true, Channels: lib.Channel{Email: &off, Sms: &off, Chat: &off}}},
+	}
+
+	resolved := lib.ResolvePreferences(workflows, &lib.Preference{Enabled: true, Channels: lib.Channel{Push: &off}}, subscriber)
+
+	assert.Len(t, resolved, 2)
+	assert.Equal(t, []lib.ChannelType{lib.INAPP}, resolved[0].EnabledChannels())
+	assert.Equal(t, lib.PreferenceChannels, resolved[1].EnabledChannels())
+}
@@ -0,0 +1,268 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// PreferenceChannels are the channels a subscriber can opt in and out of.
+var PreferenceChannels = []ChannelType{EMAIL, SMS, CHAT, INAPP, PUSH}
+
+// DefaultPreferenceUpdateConcurrency is the number of workflow preference
+// updates in flight during UpdatePreferencesBulk.
+const DefaultPreferenceUpdateConcurrency = 4
+
+// Get returns the setting of a channel, nil when it is not set.
+func (c Channel) Get(channel ChannelType) *bool {
+	switch channel {
+	case EMAIL:
+		return c.Email
+	case SMS:
+		return c.Sms
+	case CHAT:
+		return c.Chat
+	case INAPP:
+		return c.InApp
+	case PUSH:
+		return c.Push
+	}
+	return nil
+}
+
+// Set sets the setting of a channel, nil unsetting it.
+func (c *Channel) Set(channel ChannelType, enabled *bool) {
+	switch channel {
+	case EMAIL:
+		c.Email = enabled
+	case SMS:
+		c.Sms = enabled
+	case CHAT:
+		c.Chat = enabled
+	case INAPP:
+		c.InApp = enabled
+	case PUSH:
+		c.Push = enabled
+	}
+}
+
+type GlobalPreferencesResponse struct {
+	Data []SubscriberPreference `json:"data"`
+}
+
+type UpdateGlobalPreferencesResponse struct {
+	Data SubscriberPreference `json:"data"`
+}
+
+// UpdateGlobalPreferencesOptions changes the preferences of a subscriber
+// across every workflow. Enabled turns all notifications on or off.
+type UpdateGlobalPreferencesOptions struct {
+	Enabled     *bool                                `json:"enabled,omitempty"`
+	Preferences []UpdateSubscriberPreferencesChannel `json:"preferences,omitempty"`
+}
+
+// GetGlobalPreferences returns the channel defaults of the subscriber, which
+// apply to every workflow.
+func (s *SubscriberService) GetGlobalPreferences(ctx context.Context, subscriberID string) (*Preference, error) {
+	var resp GlobalPreferencesResponse
+	URL := s.client.config.BackendURL.JoinPath("subscribers", subscriberID, "preferences", "global")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, URL.String(), http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.client.sendRequest(req, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Data) == 0 {
+		return &Preference{Enabled: true}, nil
+	}
+
+	return &resp.Data[0].Preference, nil
+}
+
+// UpdateGlobalPreferences updates the channel defaults of the subscriber.
+func (s *SubscriberService) UpdateGlobalPreferences(ctx context.Context, subscriberID string, opts UpdateGlobalPreferencesOptions) (*Preference, error) {
+	var resp UpdateGlobalPreferencesResponse
+	URL := s.client.config.BackendURL.JoinPath("subscribers", subscriberID, "preferences")
+
+	jsonBody, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, URL.String(), bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.client.sendRequest(req, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp.Data.Preference, nil
+}
+
+// WorkflowPreferences is a row of a PreferenceMatrix. Channels only lists the
+// channels the workflow has steps for.
+type WorkflowPreferences struct {
+	WorkflowID string               `json:"workflowId"`
+	Name       string               `json:"name"`
+	Critical   bool                 `json:"critical"`
+	Enabled    bool                 `json:"enabled"`
+	Channels   map[ChannelType]bool `json:"channels"`
+}
+
+// PreferenceMatrix lays out the preferences of a subscriber as workflow ×
+// channel, next to the global channel defaults.
+type PreferenceMatrix struct {
+	Global    Preference            `json:"global"`
+	Workflows []WorkflowPreferences `json:"workflows"`
+}
+
+// Workflow returns the row of a workflow.
+func (m *PreferenceMatrix) Workflow(workflowID string) (WorkflowPreferences, bool) {
+	for _, row := range m.Workflows {
+		if row.WorkflowID == workflowID {
+			return row, true
+		}
+	}
+	return WorkflowPreferences{}, false
+}
+
+// Enabled reports whether the subscriber receives a workflow on a channel.
+// Critical workflows are always received. ok is false when the workflow or the
+// channel is not part of the matrix.
+func (m *PreferenceMatrix) Enabled(workflowID string, channel ChannelType) (enabled bool, ok bool) {
+	row, ok := m.Workflow(workflowID)
+	if !ok {
+		return false, false
+	}
+
+	enabled, ok = row.Channels[channel]
+	if !ok {
+		return false, false
+	}
+
+	return row.Critical || (m.Global.Enabled && row.Enabled && enabled), true
+}
+
+// NewPreferenceMatrix builds the matrix of the given preferences, sorted by
+// workflow name.
+func NewPreferenceMatrix(global Preference, preferences []SubscriberPreference) *PreferenceMatrix {
+	matrix := &PreferenceMatrix{Global: global}
+	for _, preference := range preferences {
+		row := WorkflowPreferences{
+			WorkflowID: preference.Template.ID,
+			Name:       preference.Template.Name,
+			Critical:   preference.Template.Critical,
+			Enabled:    preference.Preference.Enabled,
+			Channels:   make(map[ChannelType]bool),
+		}
+		for _, channel := range PreferenceChannels {
+			if enabled := preference.Preference.Channels.Get(channel); enabled != nil {
+				row.Channels[channel] = *enabled
+			}
+		}
+		matrix.Workflows = append(matrix.Workflows, row)
+	}
+
+	sort.SliceStable(matrix.Workflows, func(i, j int) bool {
+		return matrix.Workflows[i].Name < matrix.Workflows[j].Name
+	})
+
+	return matrix
+}
+
+// GetPreferenceMatrix fetches the global and per-workflow preferences of the
+// subscriber.
+func (s *SubscriberService) GetPreferenceMatrix(ctx context.Context, subscriberID string) (*PreferenceMatrix, error) {
+	global, err := s.GetGlobalPreferences(ctx, subscriberID)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get global preferences")
+	}
+
+	preferences, err := s.GetPreferences(ctx, subscriberID)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get workflow preferences")
+	}
+
+	return NewPreferenceMatrix(*global, preferences.Data), nil
+}
+
+type WorkflowPreferenceUpdate struct {
+	WorkflowID string
+	Options    UpdateSubscriberPreferencesOptions
+}
+
+type PreferenceUpdateFailure struct {
+	WorkflowID string `json:"workflowId"`
+	Message    string `json:"message"`
+}
+
+// PreferenceBulkUpdateReport lists the outcome of UpdatePreferencesBulk in the
+// order of the updates.
+type PreferenceBulkUpdateReport struct {
+	Updated []SubscriberPreference
+	Failed  []PreferenceUpdateFailure
+}
+
+// UpdatePreferencesBulk applies many workflow preference changes. Novu takes
+// them one workflow at a time, so they are sent concurrently and a failing
+// workflow does not stop the others. Only context cancellation is returned
+// as an error.
+func (s *SubscriberService) UpdatePreferencesBulk(ctx context.Context, subscriberID string, updates []WorkflowPreferenceUpdate) (*PreferenceBulkUpdateReport, error) {
+	type result struct {
+		preference *SubscriberPreference
+		err        error
+	}
+	results := make([]result, len(updates))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, DefaultPreferenceUpdateConcurrency)
+	for i, update := range updates {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, update WorkflowPreferenceUpdate) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			opts := update.Options
+			resp, err := s.UpdatePreferences(ctx, subscriberID, update.WorkflowID, &opts)
+			if err != nil {
+				results[i].err = err
+				return
+			}
+			results[i].preference = &resp.Data
+		}(i, update)
+	}
+	wg.Wait()
+
+	report := &PreferenceBulkUpdateReport{}
+	for i, r := range results {
+		switch {
+		case r.preference != nil:
+			report.Updated = append(report.Updated, *r.preference)
+		case r.err != nil:
+			report.Failed = append(report.Failed, PreferenceUpdateFailure{WorkflowID: updates[i].WorkflowID, Message: r.err.Error()})
+		}
+	}
+
+	return report, ctx.Err()
+}
@@ -0,0 +1,144 @@
+package lib_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/saeid-a/go-novu/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriberService_GetGlobalPreferences(t *testing.T) {
+	var response lib.GlobalPreferencesResponse
+	fileToStruct(filepath.Join("../testdata", "subscriber_global_preferences_response.json"), &response)
+
+	httpServer := createTestServer(t, TestServerOptions[io.Reader, lib.GlobalPreferencesResponse]{
+		expectedURLPath:    fmt.Sprintf("/v1/subscribers/%s/preferences/global", subscriberID),
+		expectedSentMethod: http.MethodGet,
+		expectedSentBody:   http.NoBody,
+		responseStatusCode: http.StatusOK,
+		responseBody:       response,
+	})
+
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+	preference, err := c.SubscriberApi.GetGlobalPreferences(context.Background(), subscriberID)
+	require.NoError(t, err)
+
+	assert.True(t, preference.Enabled)
+	assert.Equal(t, true, *preference.Channels.Get(lib.EMAIL))
+	assert.Equal(t, false, *preference.Channels.Get(lib.SMS))
+}
+
+func TestSubscriberService_UpdateGlobalPreferences(t *testing.T) {
+	enabled := false
+	opts := lib.UpdateGlobalPreferencesOptions{
+		Enabled:     &enabled,
+		Preferences: []lib.UpdateSubscriberPreferencesChannel{{Type: lib.EMAIL, Enabled: false}},
+	}
+	response := lib.UpdateGlobalPreferencesResponse{Data: lib.SubscriberPreference{Preference: lib.Preference{Enabled: false}}}
+
+	httpServer := createTestServer(t, TestServerOptions[lib.UpdateGlobalPreferencesOptions, lib.UpdateGlobalPreferencesResponse]{
+		expectedURLPath:    fmt.Sprintf("/v1/subscribers/%s/preferences", subscriberID),
+		expectedSentMethod: http.MethodPatch,
+		expectedSentBody:   opts,
+		responseStatusCode: http.StatusOK,
+		responseBody:       response,
+	})
+
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+	preference, err := c.SubscriberApi.UpdateGlobalPreferences(context.Background(), subscriberID, opts)
+	require.NoError(t, err)
+	assert.False(t, preference.Enabled)
+}
+
+func TestSubscriberService_GetPreferenceMatrix(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/subscribers/"+subscriberID+"/preferences/global", func(w http.ResponseWriter, req *http.Request) {
+		http.ServeFile(w, req, "../testdata/subscriber_global_preferences_response.json")
+	})
+	mux.HandleFunc("/v1/subscribers/"+subscriberID+"/preferences", func(w http.ResponseWriter, req *http.Request) {
+		http.ServeFile(w, req, "../testdata/subscriber_preferences_response.json")
+	})
+	httpServer := httptest.NewServer(mux)
+	defer httpServer.Close()
+
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+	matrix, err := c.SubscriberApi.GetPreferenceMatrix(context.Background(), subscriberID)
+	require.NoError(t, err)
+
+	require.NotEmpty(t, matrix.Workflows)
+	assert.Equal(t, "Build Events", matrix.Workflows[0].Name)
+
+	enabled, ok := matrix.Enabled("6c65c6b0eaf3900ecab1aa33", lib.INAPP)
+	assert.True(t, ok)
+	assert.True(t, enabled)
+
+	_, ok = matrix.Enabled("6c65c6b0eaf3900ecab1aa33", lib.SMS)
+	assert.False(t, ok)
+
+	row, ok := matrix.Workflow("5c1c5b8d98c1a7f4fb5fbd2e")
+	require.True(t, ok)
+	assert.True(t, row.Critical)
+	assert.Equal(t, map[lib.ChannelType]bool{lib.EMAIL: true, lib.CHAT: true}, row.Channels)
+}
+
+func TestPreferenceMatrix_Enabled_GlobalOff(t *testing.T) {
+	on := true
+	preferences := []lib.SubscriberPreference{
+		{Template: lib.Template{ID: "digest", Name: "Digest"}, Preference: lib.Preference{Enabled: true, Channels: lib.Channel{Email: &on}}},
+		{Template: lib.Template{ID: "security", Name: "Security", Critical: true}, Preference: lib.Preference{Enabled: true, Channels: lib.Channel{Email: &on}}},
+	}
+	matrix := lib.NewPreferenceMatrix(lib.Preference{Enabled: false}, preferences)
+
+	enabled, _ := matrix.Enabled("digest", lib.EMAIL)
+	assert.False(t, enabled)
+	enabled, _ = matrix.Enabled("security", lib.EMAIL)
+	assert.True(t, enabled)
+}
+
+func TestSubscriberService_UpdatePreferencesBulk(t *testing.T) {
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodPatch, req.Method)
+		workflowID := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+		if workflowID == "broken" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"workflow not found"}`))
+			return
+		}
+
+		var opts lib.UpdateSubscriberPreferencesOptions
+		_ = json.NewDecoder(req.Body).Decode(&opts)
+		bb, _ := json.Marshal(lib.UpdateSubscriberPreferencesResponse{Data: lib.SubscriberPreference{
+			Template:   lib.Template{ID: workflowID},
+			Preference: lib.Preference{Enabled: *opts.Enabled},
+		}})
+		w.Write(bb)
+	}))
+	defer httpServer.Close()
+
+	off := false
+	updates := []lib.WorkflowPreferenceUpdate{
+		{WorkflowID: "first", Options: lib.UpdateSubscriberPreferencesOptions{Enabled: &off}},
+		{WorkflowID: "broken", Options: lib.UpdateSubscriberPreferencesOptions{Enabled: &off}},
+		{WorkflowID: "second", Options: lib.UpdateSubscriberPreferencesOptions{Enabled: &off}},
+	}
+
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+	report, err := c.SubscriberApi.UpdatePreferencesBulk(context.Background(), subscriberID, updates)
+	require.NoError(t, err)
+
+	require.Len(t, report.Updated, 2)
+	assert.Equal(t, "first", report.Updated[0].Template.ID)
+	assert.Equal(t, "second", report.Updated[1].Template.ID)
+	require.Len(t, report.Failed, 1)
+	assert.Equal(t, "broken", report.Failed[0].WorkflowID)
+	assert.Contains(t, report.Failed[0].Message, "workflow not found")
+}
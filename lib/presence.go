@@ -0,0 +1,152 @@
+package lib
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultPresenceCacheTTL   = 30 * time.Second
+	DefaultPresenceStaleAfter = 10 * time.Minute
+)
+
+// PresenceRoutingOptions configures how a PresenceRouter picks the delivery
+// for online and offline subscribers.
+type PresenceRoutingOptions struct {
+	// OnlineWorkflow and OfflineWorkflow are the workflow identifiers used for
+	// each state. An empty identifier keeps the event id passed to Trigger.
+	OnlineWorkflow  string
+	OfflineWorkflow string
+
+	// OnlineOverrides and OfflineOverrides are merged into the trigger
+	// overrides for each state, e.g. to skip the push step of a workflow.
+	OnlineOverrides  map[string]interface{}
+	OfflineOverrides map[string]interface{}
+
+	// StaleAfter treats an online subscriber as offline when its lastOnlineAt
+	// is older than this. Defaults to DefaultPresenceStaleAfter, a negative
+	// value disables the check.
+	StaleAfter time.Duration
+
+	// CacheTTL is how long a subscriber presence is cached. Defaults to
+	// DefaultPresenceCacheTTL, a negative value disables caching.
+	CacheTTL time.Duration
+}
+
+// PresenceRoute is the delivery picked for a subscriber.
+type PresenceRoute struct {
+	Online    bool
+	Workflow  string
+	Overrides map[string]interface{}
+}
+
+type presenceEntry struct {
+	online    bool
+	expiresAt time.Time
+}
+
+// PresenceRouter routes triggers according to the subscriber isOnline and
+// lastOnlineAt fields, e.g. in-app only when online and push when offline.
+type PresenceRouter struct {
+	client *APIClient
+	opts   PresenceRoutingOptions
+	now    func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]presenceEntry
+}
+
+func NewPresenceRouter(client *APIClient, opts PresenceRoutingOptions) *PresenceRouter {
+	if opts.StaleAfter == 0 {
+		opts.StaleAfter = DefaultPresenceStaleAfter
+	}
+	if opts.CacheTTL == 0 {
+		opts.CacheTTL = DefaultPresenceCacheTTL
+	}
+
+	return &PresenceRouter{
+		client: client,
+		opts:   opts,
+		now:    time.Now,
+		cache:  make(map[string]presenceEntry),
+	}
+}
+
+// IsOnline reports whether the subscriber is currently online.
+func (r *PresenceRouter) IsOnline(ctx context.Context, subscriberID string) (bool, error) {
+	now := r.now()
+
+	r.mu.Lock()
+	entry, ok := r.cache[subscriberID]
+	r.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.online, nil
+	}
+
+	resp, err := r.client.SubscriberApi.Get(ctx, subscriberID)
+	if err != nil {
+		return false, errors.Wrap(err, "unable to get subscriber presence")
+	}
+
+	online := resp.Data.IsOnline
+	if online && r.opts.StaleAfter > 0 && resp.Data.LastOnlineAt != nil && now.Sub(*resp.Data.LastOnlineAt) > r.opts.StaleAfter {
+		online = false
+	}
+
+	if r.opts.CacheTTL > 0 {
+		r.mu.Lock()
+		r.cache[subscriberID] = presenceEntry{online: online, expiresAt: now.Add(r.opts.CacheTTL)}
+		r.mu.Unlock()
+	}
+
+	return online, nil
+}
+
+// Forget drops the cached presence of a subscriber.
+func (r *PresenceRouter) Forget(subscriberID string) {
+	r.mu.Lock()
+	delete(r.cache, subscriberID)
+	r.mu.Unlock()
+}
+
+// Route returns the workflow and overrides to use for the subscriber.
+func (r *PresenceRouter) Route(ctx context.Context, subscriberID string) (PresenceRoute, error) {
+	online, err := r.IsOnline(ctx, subscriberID)
+	if err != nil {
+		return PresenceRoute{}, err
+	}
+
+	if online {
+		return PresenceRoute{Online: true, Workflow: r.opts.OnlineWorkflow, Overrides: r.opts.OnlineOverrides}, nil
+	}
+
+	return PresenceRoute{Online: false, Workflow: r.opts.OfflineWorkflow, Overrides: r.opts.OfflineOverrides}, nil
+}
+
+// Trigger routes the subscriber and triggers the selected workflow, falling
+// back to eventId when no workflow is configured for the subscriber state.
+// data.To defaults to the subscriber id.
+func (r *PresenceRouter) Trigger(ctx context.Context, eventId string, subscriberID string, data ITriggerPayloadOptions) (EventResponse, error) {
+	route, err := r.Route(ctx, subscriberID)
+	if err != nil {
+		return EventResponse{}, err
+	}
+
+	if route.Workflow != "" {
+		eventId = route.Workflow
+	}
+	if data.To == nil {
+		data.To = subscriberID
+	}
+	if len(route.Overrides) > 0 {
+		data.Overrides, err = r.client.mergeStruct(data.Overrides, route.Overrides)
+		if err != nil {
+			return EventResponse{}, errors.Wrap(err, "unable to merge overrides")
+		}
+	}
+
+	return r.client.EventApi.Trigger(ctx, eventId, data)
+}
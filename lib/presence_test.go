@@ -0,0 +1,95 @@
+package lib_test
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/saeid-a/go-novu/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPresenceRouter_Trigger_Success(t *testing.T) {
+	var (
+		subscriberRequests int
+		triggered          []lib.EventRequest
+	)
+
+	presence := map[string]map[string]interface{}{
+		"online-user": {
+			"subscriberId": "online-user",
+			"isOnline":     true,
+			"lastOnlineAt": time.Now().Add(-time.Minute).UTC().Format(time.RFC3339),
+		},
+		"stale-user": {
+			"subscriberId": "stale-user",
+			"isOnline":     true,
+			"lastOnlineAt": time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if strings.HasPrefix(req.RequestURI, "/v1/subscribers/") {
+			subscriberRequests++
+			assert.Equal(t, http.MethodGet, req.Method)
+
+			id := strings.TrimPrefix(req.RequestURI, "/v1/subscribers/")
+			w.WriteHeader(http.StatusOK)
+			bb, _ := json.Marshal(lib.JsonResponse{Data: presence[id]})
+			w.Write(bb)
+			return
+		}
+
+		var receivedBody lib.EventRequest
+		if err := json.NewDecoder(req.Body).Decode(&receivedBody); err != nil {
+			log.Printf("error in unmarshalling %+v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		triggered = append(triggered, receivedBody)
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"data":{"acknowledged":true,"status":"processed"}}`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(server.URL)})
+	router := lib.NewPresenceRouter(c, lib.PresenceRoutingOptions{
+		OnlineWorkflow:   "comment-in-app",
+		OfflineWorkflow:  "comment-push",
+		OfflineOverrides: map[string]interface{}{"fcm": map[string]interface{}{"priority": "high"}},
+		StaleAfter:       15 * time.Minute,
+	})
+
+	_, err := router.Trigger(ctx, "comment", "online-user", lib.ITriggerPayloadOptions{})
+	require.NoError(t, err)
+	_, err = router.Trigger(ctx, "comment", "online-user", lib.ITriggerPayloadOptions{})
+	require.NoError(t, err)
+	_, err = router.Trigger(ctx, "comment", "stale-user", lib.ITriggerPayloadOptions{
+		Overrides: map[string]interface{}{"email": map[string]interface{}{"replyTo": "noreply@example.com"}},
+	})
+	require.NoError(t, err)
+
+	t.Run("Presence is cached", func(t *testing.T) {
+		assert.Equal(t, 2, subscriberRequests)
+	})
+
+	require.Len(t, triggered, 3)
+	assert.Equal(t, "comment-in-app", triggered[0].Name)
+	assert.Equal(t, "online-user", triggered[0].To)
+	assert.Nil(t, triggered[0].Overrides)
+
+	assert.Equal(t, "comment-push", triggered[2].Name)
+	assert.Equal(t, "stale-user", triggered[2].To)
+	assert.Equal(t, map[string]interface{}{
+		"email": map[string]interface{}{"replyTo": "noreply@example.com"},
+		"fcm":   map[string]interface{}{"priority": "high"},
+	}, triggered[2].Overrides)
+}
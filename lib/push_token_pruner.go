@@ -0,0 +1,331 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultInvalidTokenErrors are the provider errors meaning a push token will
+// never be delivered to again.
+var DefaultInvalidTokenErrors = []string{
+	// FCM
+	"registration-token-not-registered",
+	"invalid-registration-token",
+	"NotRegistered",
+	"InvalidRegistration",
+	"UNREGISTERED",
+	// APNS
+	"BadDeviceToken",
+	"Unregistered",
+	"DeviceTokenNotForTopic",
+	// Expo
+	"DeviceNotRegistered",
+}
+
+// PushTokenPrunerOptions configures a PushTokenPruner.
+type PushTokenPrunerOptions struct {
+	// SubscriberId restricts the scan to a single subscriber.
+	SubscriberId string
+
+	// Providers are the push providers whose tokens are pruned. Defaults to
+	// fcm, apns and expo.
+	Providers []ProviderIdType
+
+	// InvalidTokenErrors are matched against the execution details of failed
+	// deliveries. Defaults to DefaultInvalidTokenErrors.
+	InvalidTokenErrors []string
+
+	// PageSize and MaxPages bound the scan of push messages. MaxPages zero
+	// scans every page.
+	PageSize int
+	MaxPages int
+
+	// DryRun reports the dead tokens without removing them.
+	DryRun bool
+}
+
+type DeadPushToken struct {
+	SubscriberId string         `json:"subscriberId"`
+	ProviderId   ProviderIdType `json:"providerId"`
+	Token        string         `json:"token"`
+	MessageId    string         `json:"messageId"`
+	Reason       string         `json:"reason"`
+}
+
+type PushTokenPruneReport struct {
+	DryRun  bool
+	Scanned int
+	Found   []DeadPushToken
+	Removed []DeadPushToken
+	Failed  []SubscriberBulkFailure
+}
+
+// PushTokenPruner scans the failed push deliveries for invalid token errors
+// and removes the tokens from the subscriber credentials.
+type PushTokenPruner struct {
+	client *APIClient
+	opts   PushTokenPrunerOptions
+}
+
+func NewPushTokenPruner(client *APIClient, opts PushTokenPrunerOptions) *PushTokenPruner {
+	if len(opts.Providers) == 0 {
+		opts.Providers = []ProviderIdType{ProviderFCM, ProviderAPNS, ProviderExpo}
+	}
+	if len(opts.InvalidTokenErrors) == 0 {
+		opts.InvalidTokenErrors = DefaultInvalidTokenErrors
+	}
+	if opts.PageSize <= 0 {
+		opts.PageSize = DefaultSubscribersPageSize
+	}
+
+	return &PushTokenPruner{client: client, opts: opts}
+}
+
+type pushMessage struct {
+	Id             string         `json:"_id"`
+	NotificationId string         `json:"_notificationId"`
+	ProviderId     ProviderIdType `json:"providerId"`
+	Status         string         `json:"status"`
+	ErrorText      string         `json:"errorText"`
+	DeviceTokens   []string       `json:"deviceTokens"`
+	Subscriber     struct {
+		SubscriberId string `json:"subscriberId"`
+	} `json:"subscriber"`
+}
+
+type executionDetail struct {
+	MessageId  string         `json:"_messageId"`
+	ProviderId ProviderIdType `json:"providerId"`
+	Status     string         `json:"status"`
+	Detail     string         `json:"detail"`
+	Raw        string         `json:"raw"`
+}
+
+// Run scans the push messages and prunes the dead tokens it finds.
+func (p *PushTokenPruner) Run(ctx context.Context) (*PushTokenPruneReport, error) {
+	report := &PushTokenPruneReport{DryRun: p.opts.DryRun}
+
+	found, err := p.scan(ctx, report)
+	if err != nil {
+		return report, err
+	}
+	if p.opts.DryRun {
+		return report, nil
+	}
+
+	subscriberIds := make([]string, 0, len(found))
+	for subscriberId := range found {
+		subscriberIds = append(subscriberIds, subscriberId)
+	}
+	sort.Strings(subscriberIds)
+
+	for _, subscriberId := range subscriberIds {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		removed, err := p.prune(ctx, subscriberId, found[subscriberId])
+		if err != nil {
+			report.Failed = append(report.Failed, SubscriberBulkFailure{SubscriberId: subscriberId, Message: err.Error()})
+			continue
+		}
+		report.Removed = append(report.Removed, removed...)
+	}
+
+	return report, nil
+}
+
+// scan returns the dead tokens found, grouped by subscriber.
+func (p *PushTokenPruner) scan(ctx context.Context, report *PushTokenPruneReport) (map[string][]DeadPushToken, error) {
+	found := make(map[string][]DeadPushToken)
+	seen := make(map[DeadPushToken]bool)
+
+	for page := 0; p.opts.MaxPages == 0 || page < p.opts.MaxPages; page++ {
+		resp, err := p.client.MessagesApi.GetMessages(ctx, MessagesQueryParams{
+			Channel:      string(PUSH),
+			SubscriberId: p.opts.SubscriberId,
+			Page:         page,
+			Limit:        p.opts.PageSize,
+		})
+		if err != nil {
+			return found, errors.Wrapf(err, "unable to list push messages page %d", page)
+		}
+
+		var messages []pushMessage
+		if err := decodeJsonResponse(resp, &messages); err != nil {
+			return found, errors.Wrap(err, "unable to decode push messages")
+		}
+
+		for _, message := range messages {
+			report.Scanned++
+			if message.Status == "sent" || !p.prunable(message.ProviderId) || len(message.DeviceTokens) == 0 {
+				continue
+			}
+
+			tokens, err := p.deadTokens(ctx, message)
+			if err != nil {
+				return found, err
+			}
+			for _, token := range tokens {
+				key := token
+				key.MessageId, key.Reason = "", ""
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				report.Found = append(report.Found, token)
+				found[token.SubscriberId] = append(found[token.SubscriberId], token)
+			}
+		}
+
+		if len(messages) < p.opts.PageSize {
+			break
+		}
+	}
+
+	return found, nil
+}
+
+// deadTokens maps the invalid token errors of a message back to its tokens. A
+// token is dead when the error mentions it, or when the message was sent to a
+// single token.
+func (p *PushTokenPruner) deadTokens(ctx context.Context, message pushMessage) ([]DeadPushToken, error) {
+	resp, err := p.client.ExecutionsApi.GetExecutions(ctx, ExecutionsQueryParams{
+		NotificationId: message.NotificationId,
+		SubscriberId:   message.Subscriber.SubscriberId,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get executions of message %s", message.Id)
+	}
+
+	var details []executionDetail
+	if err := decodeJsonResponse(resp, &details); err != nil {
+		return nil, errors.Wrap(err, "unable to decode execution details")
+	}
+
+	failures := []string{message.ErrorText}
+	for _, detail := range details {
+		if detail.MessageId != "" && detail.MessageId != message.Id {
+			continue
+		}
+		failures = append(failures, detail.Detail+" "+detail.Raw)
+	}
+
+	var dead []DeadPushToken
+	for _, failure := range failures {
+		reason := p.invalidTokenError(failure)
+		if reason == "" {
+			continue
+		}
+
+		matched := false
+		for _, token := range message.DeviceTokens {
+			if strings.Contains(failure, token) {
+				dead = append(dead, p.deadToken(message, token, reason))
+				matched = true
+			}
+		}
+		if !matched && len(message.DeviceTokens) == 1 {
+			dead = append(dead, p.deadToken(message, message.DeviceTokens[0], reason))
+		}
+	}
+
+	return dead, nil
+}
+
+func (p *PushTokenPruner) deadToken(message pushMessage, token string, reason string) DeadPushToken {
+	return DeadPushToken{
+		SubscriberId: message.Subscriber.SubscriberId,
+		ProviderId:   message.ProviderId,
+		Token:        token,
+		MessageId:    message.Id,
+		Reason:       reason,
+	}
+}
+
+// prune removes the dead tokens of a subscriber, integration by integration.
+// The removal goes through the same read-merge-write as RemoveDeviceToken, so
+// a token registered while the pruner runs is kept.
+func (p *PushTokenPruner) prune(ctx context.Context, subscriberId string, dead []DeadPushToken) ([]DeadPushToken, error) {
+	resp, err := p.client.SubscriberApi.Get(ctx, subscriberId)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []DeadPushToken
+	for _, channel := range resp.Data.Channels {
+		var pruned []DeadPushToken
+		for _, token := range channel.Credentials.DeviceTokens {
+			if i := indexDeadToken(dead, channel.ProviderId, token); i >= 0 {
+				pruned = append(pruned, dead[i])
+			}
+		}
+		if len(pruned) == 0 {
+			continue
+		}
+
+		// The credentials are only updated for the integration holding the
+		// dead tokens, the other integrations of the provider keep theirs.
+		opts := deviceTokenOptions(&DeviceTokenOptions{IntegrationIdentifier: channel.IntegrationIdentifier})
+		_, err := p.client.SubscriberApi.updateDeviceTokens(ctx, subscriberId, channel.ProviderId, opts, func(tokens []string) []string {
+			for _, token := range pruned {
+				tokens = removeToken(tokens, token.Token)
+			}
+			return tokens
+		}, func(tokens []string) bool {
+			for _, token := range pruned {
+				if containsToken(tokens, token.Token) {
+					return false
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return removed, errors.Wrapf(err, "unable to update %s credentials", channel.ProviderId)
+		}
+		removed = append(removed, pruned...)
+	}
+
+	return removed, nil
+}
+
+func (p *PushTokenPruner) prunable(providerId ProviderIdType) bool {
+	for _, provider := range p.opts.Providers {
+		if provider == providerId {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *PushTokenPruner) invalidTokenError(failure string) string {
+	for _, invalid := range p.opts.InvalidTokenErrors {
+		if strings.Contains(failure, invalid) {
+			return invalid
+		}
+	}
+	return ""
+}
+
+func indexDeadToken(dead []DeadPushToken, providerId ProviderIdType, token string) int {
+	for i, d := range dead {
+		if d.ProviderId == providerId && d.Token == token {
+			return i
+		}
+	}
+	return -1
+}
+
+func decodeJsonResponse(resp JsonResponse, v interface{}) error {
+	b, err := json.Marshal(resp.Data)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,139 @@
+package lib_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/saeid-a/go-novu/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const pushMessagesResponse = `{
+	"data": [
+		{"_id": "m1", "_notificationId": "n1", "providerId": "fcm", "status": "error", "deviceTokens": ["dead", "live"], "subscriber": {"subscriberId": "alice"}},
+		{"_id": "m2", "_notificationId": "n2", "providerId": "apns", "status": "error", "errorText": "BadDeviceToken", "deviceTokens": ["apple"], "subscriber": {"subscriberId": "alice"}},
+		{"_id": "m3", "_notificationId": "n3", "providerId": "fcm", "status": "sent", "deviceTokens": ["ok"], "subscriber": {"subscriberId": "bob"}},
+		{"_id": "m4", "_notificationId": "n4", "providerId": "fcm", "status": "error", "deviceTokens": ["flaky"], "subscriber": {"subscriberId": "bob"}}
+	]
+}`
+
+// pushTokenServer serves alice with two fcm integrations, only one of them
+// holding a dead token, and records the credentials updates. added, when set,
+// is registered on that integration right after alice is first read, like a
+// device registering while the pruner runs.
+func pushTokenServer(t *testing.T, updates *[]lib.SubscriberCredentialPayload, added string) *httptest.Server {
+	var mu sync.Mutex
+	channels := []lib.SubscriberChannel{
+		{ProviderId: lib.ProviderFCM, IntegrationIdentifier: "fcm-app", Credentials: lib.Credentials{DeviceTokens: []string{"dead"}}},
+		{ProviderId: lib.ProviderFCM, IntegrationIdentifier: "fcm-web", Credentials: lib.Credentials{DeviceTokens: []string{"live"}}},
+		{ProviderId: lib.ProviderAPNS, Credentials: lib.Credentials{DeviceTokens: []string{"apple"}}},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/messages", func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "push", req.URL.Query().Get("channel"))
+		w.Write([]byte(pushMessagesResponse))
+	})
+	mux.HandleFunc("/v1/execution-details", func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Query().Get("notificationId") {
+		case "n1":
+			w.Write([]byte(`{"data": [{"_messageId": "m1", "status": "Failed", "detail": "Unexpected provider error", "raw": "{\"token\":\"dead\",\"code\":\"messaging/registration-token-not-registered\"}"}]}`))
+		case "n4":
+			w.Write([]byte(`{"data": [{"_messageId": "m4", "status": "Failed", "detail": "Unexpected provider error", "raw": "{\"code\":\"messaging/internal-error\"}"}]}`))
+		default:
+			w.Write([]byte(`{"data": []}`))
+		}
+	})
+	mux.HandleFunc("/v1/subscribers/alice", func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		bb, _ := json.Marshal(lib.SubscriberResponse{Data: lib.Subscriber{SubscriberId: "alice", Channels: channels}})
+		w.Write(bb)
+
+		if added != "" {
+			channels[0].Credentials.DeviceTokens = append(channels[0].Credentials.DeviceTokens, added)
+			added = ""
+		}
+	})
+	mux.HandleFunc("/v1/subscribers/alice/credentials", func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		assert.Equal(t, http.MethodPut, req.Method)
+		var payload lib.SubscriberCredentialPayload
+		_ = json.NewDecoder(req.Body).Decode(&payload)
+		*updates = append(*updates, payload)
+		for i := range channels {
+			if channels[i].ProviderId == payload.ProviderId && channels[i].IntegrationIdentifier == payload.IntegrationIdentifier {
+				channels[i].Credentials = payload.Credentials
+			}
+		}
+		w.Write([]byte(`{"data": {}}`))
+	})
+	mux.HandleFunc("/v1/subscribers/alice/credentials/", func(w http.ResponseWriter, req *http.Request) {
+		t.Errorf("unexpected request %s %s", req.Method, req.URL)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestPushTokenPruner_Run(t *testing.T) {
+	var updates []lib.SubscriberCredentialPayload
+	server := pushTokenServer(t, &updates, "")
+
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(server.URL)})
+	report, err := lib.NewPushTokenPruner(c, lib.PushTokenPrunerOptions{}).Run(context.Background())
+	require.NoError(t, err)
+
+	expected := []lib.DeadPushToken{
+		{SubscriberId: "alice", ProviderId: lib.ProviderFCM, Token: "dead", MessageId: "m1", Reason: "registration-token-not-registered"},
+		{SubscriberId: "alice", ProviderId: lib.ProviderAPNS, Token: "apple", MessageId: "m2", Reason: "BadDeviceToken"},
+	}
+	assert.Equal(t, 4, report.Scanned)
+	assert.Equal(t, expected, report.Found)
+	assert.Equal(t, expected, report.Removed)
+	assert.Empty(t, report.Failed)
+
+	assert.Equal(t, []lib.SubscriberCredentialPayload{
+		lib.FCMCredentials().WithIntegration("fcm-app"),
+		lib.APNSCredentials(),
+	}, updates, "only the fcm integration holding the dead token is cleared")
+}
+
+func TestPushTokenPruner_Run_DryRun(t *testing.T) {
+	var updates []lib.SubscriberCredentialPayload
+	server := pushTokenServer(t, &updates, "")
+
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(server.URL)})
+	report, err := lib.NewPushTokenPruner(c, lib.PushTokenPrunerOptions{DryRun: true}).Run(context.Background())
+	require.NoError(t, err)
+
+	assert.True(t, report.DryRun)
+	assert.Len(t, report.Found, 2)
+	assert.Empty(t, report.Removed)
+	assert.Empty(t, updates)
+}
+
+func TestPushTokenPruner_Run_ConcurrentAdd(t *testing.T) {
+	var updates []lib.SubscriberCredentialPayload
+	server := pushTokenServer(t, &updates, "fresh")
+
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(server.URL)})
+	report, err := lib.NewPushTokenPruner(c, lib.PushTokenPrunerOptions{Providers: []lib.ProviderIdType{lib.ProviderFCM}}).Run(context.Background())
+	require.NoError(t, err)
+
+	assert.Len(t, report.Removed, 1)
+	assert.Equal(t, []lib.SubscriberCredentialPayload{
+		lib.FCMCredentials("fresh").WithIntegration("fcm-app"),
+	}, updates, "the token registered during the prune is kept")
+}
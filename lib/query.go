@@ -0,0 +1,196 @@
+package lib
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// EncodeQuery encodes a struct into query parameters. Fields are named by
+// their queryKey tag, or by the field name when it has none, and the tag
+// accepts the following options after the name:
+//
+//	omitempty  skips the zero value of the field
+//	json       sends the value as a JSON string
+//	base64     sends the value as base64 encoded JSON
+//
+// A nil pointer is always skipped while a non-nil one is always sent, which
+// makes *bool fields tri-state. Slices repeat the key for every element,
+// time.Time is sent as RFC 3339 and nested structs are flattened as
+// parent[child]. A field tagged queryKey:"-" is ignored.
+func EncodeQuery(v interface{}) (url.Values, error) {
+	values := url.Values{}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return values, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, errors.Errorf("query must be a struct, got %s", rv.Kind())
+	}
+
+	if err := encodeQueryStruct(values, "", rv); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+type queryTag struct {
+	name      string
+	omitEmpty bool
+	json      bool
+	base64    bool
+}
+
+func parseQueryTag(field reflect.StructField) queryTag {
+	parts := strings.Split(field.Tag.Get("queryKey"), ",")
+
+	tag := queryTag{name: parts[0]}
+	if tag.name == "" {
+		tag.name = field.Name
+	}
+	for _, option := range parts[1:] {
+		switch option {
+		case "omitempty":
+			tag.omitEmpty = true
+		case "json":
+			tag.json = true
+		case "base64":
+			tag.base64 = true
+		}
+	}
+
+	return tag
+}
+
+func encodeQueryStruct(values url.Values, prefix string, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() || field.Tag.Get("queryKey") == "-" {
+			continue
+		}
+
+		tag := parseQueryTag(field)
+		key := tag.name
+		if prefix != "" {
+			key = prefix + "[" + key + "]"
+		}
+
+		if err := encodeQueryField(values, key, tag, rv.Field(i)); err != nil {
+			return errors.Wrapf(err, "query field %s", field.Name)
+		}
+	}
+
+	return nil
+}
+
+func encodeQueryField(values url.Values, key string, tag queryTag, fv reflect.Value) error {
+	for fv.Kind() == reflect.Pointer || fv.Kind() == reflect.Interface {
+		if fv.IsNil() {
+			return nil
+		}
+		fv = fv.Elem()
+		// A value set through a pointer is sent even when it is zero.
+		tag.omitEmpty = false
+	}
+
+	if tag.omitEmpty && fv.IsZero() {
+		return nil
+	}
+
+	if tag.json || tag.base64 {
+		b, err := json.Marshal(fv.Interface())
+		if err != nil {
+			return err
+		}
+		if tag.base64 {
+			values.Add(key, base64.StdEncoding.EncodeToString(b))
+		} else {
+			values.Add(key, string(b))
+		}
+		return nil
+	}
+
+	switch {
+	case fv.Type() == timeType || fv.Type().Implements(textMarshalerType):
+		s, err := queryScalar(fv)
+		if err != nil {
+			return err
+		}
+		values.Add(key, s)
+	case fv.Kind() == reflect.Slice || fv.Kind() == reflect.Array:
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.Uint8 {
+			values.Add(key, base64.StdEncoding.EncodeToString(fv.Bytes()))
+			return nil
+		}
+		for i := 0; i < fv.Len(); i++ {
+			elem := fv.Index(i)
+			for elem.Kind() == reflect.Pointer || elem.Kind() == reflect.Interface {
+				if elem.IsNil() {
+					break
+				}
+				elem = elem.Elem()
+			}
+			if elem.Kind() == reflect.Pointer || elem.Kind() == reflect.Interface {
+				continue
+			}
+			s, err := queryScalar(elem)
+			if err != nil {
+				return err
+			}
+			values.Add(key, s)
+		}
+	case fv.Kind() == reflect.Struct:
+		return encodeQueryStruct(values, key, fv)
+	default:
+		s, err := queryScalar(fv)
+		if err != nil {
+			return err
+		}
+		values.Add(key, s)
+	}
+
+	return nil
+}
+
+func queryScalar(v reflect.Value) (string, error) {
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).Format(time.RFC3339Nano), nil
+	}
+	if v.Type().Implements(textMarshalerType) {
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	}
+
+	return "", errors.Errorf("unsupported query type %s", v.Type())
+}
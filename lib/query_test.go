@@ -0,0 +1,89 @@
+package lib_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/saeid-a/go-novu/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeQuery(t *testing.T) {
+	yes, no, zero := true, false, 0
+	at := time.Date(2023, time.February, 17, 12, 37, 47, 0, time.UTC)
+
+	type filter struct {
+		Status string `queryKey:"status,omitempty"`
+		Tags   []string
+	}
+
+	tests := map[string]struct {
+		query interface{}
+		want  string
+	}{
+		"zero values without omitempty": {
+			query: struct {
+				Name  string `queryKey:"name"`
+				Count int    `queryKey:"count"`
+			}{},
+			want: "count=0&name=",
+		},
+		"omitempty": {
+			query: struct {
+				Name  string `queryKey:"name,omitempty"`
+				Count int    `queryKey:"count,omitempty"`
+			}{Count: 2},
+			want: "count=2",
+		},
+		"tri-state pointers": {
+			query: struct {
+				Seen  *bool `queryKey:"seen,omitempty"`
+				Read  *bool `queryKey:"read"`
+				Page  *int  `queryKey:"page"`
+				Limit *int  `queryKey:"limit"`
+			}{Seen: &no, Read: &yes, Page: &zero},
+			want: "page=0&read=true&seen=false",
+		},
+		"slices and times": {
+			query: struct {
+				Ids   []string  `queryKey:"id"`
+				After time.Time `queryKey:"after,omitempty"`
+				None  []int     `queryKey:"none"`
+			}{Ids: []string{"a", "b"}, After: at},
+			want: "after=2023-02-17T12%3A37%3A47Z&id=a&id=b",
+		},
+		"nested and json fields": {
+			query: struct {
+				Filter filter                 `queryKey:"filter"`
+				Data   map[string]interface{} `queryKey:"data,json"`
+				Skip   string                 `queryKey:"-"`
+			}{Filter: filter{Tags: []string{"x"}}, Data: map[string]interface{}{"a": 1}, Skip: "skip"},
+			want: "data=%7B%22a%22%3A1%7D&filter%5BTags%5D=x",
+		},
+		"nil pointer to struct": {
+			query: (*lib.SubscriberNotificationFeedOptions)(nil),
+			want:  "",
+		},
+		"feed seen false": {
+			query: lib.SubscriberNotificationFeedOptions{Seen: &no, Payload: map[string]interface{}{"name": "test"}},
+			want:  "payload=eyJuYW1lIjoidGVzdCJ9&seen=false",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			values, err := lib.EncodeQuery(tc.query)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, values.Encode())
+		})
+	}
+}
+
+func TestEncodeQuery_Errors(t *testing.T) {
+	_, err := lib.EncodeQuery("not a struct")
+	assert.Error(t, err)
+
+	_, err = lib.EncodeQuery(struct{ Fn func() }{Fn: func() {}})
+	assert.Error(t, err)
+}
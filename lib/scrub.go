@@ -0,0 +1,345 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type ScrubMode int
+
+const (
+	// ScrubMask replaces sensitive values with the policy mask.
+	ScrubMask ScrubMode = iota
+	// ScrubReject refuses to send a trigger containing sensitive values.
+	ScrubReject
+)
+
+const (
+	DefaultScrubMask = "[REDACTED]"
+
+	// ScrubTagKey is the struct tag marking a field as sensitive, e.g.
+	// `json:"ssn" novu:"pii"`.
+	ScrubTagKey   = "novu"
+	ScrubTagValue = "pii"
+)
+
+// Sections a ScrubPolicy path can start with.
+const (
+	ScrubSectionPayload    = "payload"
+	ScrubSectionOverrides  = "overrides"
+	ScrubSectionSubscriber = "subscriber"
+)
+
+// ScrubPolicy describes the values that must never be sent to Novu in the
+// payload, the overrides or the data of inline subscribers of a trigger. Set it
+// on Config.ScrubPolicy to apply it to every trigger sent through EventApi.
+type ScrubPolicy struct {
+	Mode ScrubMode
+
+	// Paths are dot separated JSON paths starting with a section: "payload",
+	// "overrides" or "subscriber" (the data of every inline subscriber).
+	// "*" matches a single key or list index, "**" any number of them, e.g.
+	// "payload.user.ssn", "overrides.*.notes" or "payload.**.cardNumber".
+	Paths []string
+
+	// Patterns are matched against every string value.
+	Patterns []*regexp.Regexp
+
+	// Mask replaces sensitive values in ScrubMask mode. Defaults to
+	// DefaultScrubMask.
+	Mask string
+}
+
+type ScrubViolation struct {
+	Path   string
+	Reason string
+}
+
+// ScrubError is returned in ScrubReject mode when a trigger holds sensitive
+// values.
+type ScrubError struct {
+	Violations []ScrubViolation
+}
+
+func (e *ScrubError) Error() string {
+	paths := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		paths = append(paths, fmt.Sprintf("%s (%s)", v.Path, v.Reason))
+	}
+
+	return "trigger rejected by scrub policy: " + strings.Join(paths, ", ")
+}
+
+// Apply scrubs data in place. In ScrubMask mode sensitive values are masked and
+// the violations found are returned for auditing. In ScrubReject mode data is
+// left untouched and a *ScrubError is returned when anything was found.
+//
+// A section that cannot be encoded to JSON cannot be checked: it is reported
+// as a violation and, in ScrubMask mode, dropped.
+func (p *ScrubPolicy) Apply(data *ITriggerPayloadOptions) ([]ScrubViolation, error) {
+	s := &scrubber{policy: p}
+
+	payload, payloadChanged := s.section(ScrubSectionPayload, data.Payload)
+	overrides, overridesChanged := s.section(ScrubSectionOverrides, data.Overrides)
+	to, toChanged := s.recipients(data.To)
+
+	if len(s.violations) > 0 && p.Mode == ScrubReject {
+		return s.violations, &ScrubError{Violations: s.violations}
+	}
+
+	if payloadChanged {
+		data.Payload = payload
+	}
+	if overridesChanged {
+		data.Overrides = overrides
+	}
+	if toChanged {
+		data.To = to
+	}
+
+	return s.violations, nil
+}
+
+type scrubber struct {
+	policy     *ScrubPolicy
+	tagged     map[string]bool
+	violations []ScrubViolation
+}
+
+func (s *scrubber) mask() string {
+	if s.policy.Mask != "" {
+		return s.policy.Mask
+	}
+
+	return DefaultScrubMask
+}
+
+// section scrubs one part of the trigger and reports whether it was changed.
+func (s *scrubber) section(root string, v interface{}) (interface{}, bool) {
+	if v == nil {
+		return nil, false
+	}
+
+	s.tagged = make(map[string]bool)
+	collectTaggedPaths(reflect.ValueOf(v), []string{root}, s.tagged)
+
+	generic, ok := toGeneric(v)
+	if !ok {
+		return s.unencodable(root)
+	}
+
+	return s.walk(generic, []string{root})
+}
+
+// recipients scrubs the data of the inline subscribers in a trigger "to" field.
+func (s *scrubber) recipients(to interface{}) (interface{}, bool) {
+	if to == nil {
+		return nil, false
+	}
+
+	generic, ok := toGeneric(to)
+	if !ok {
+		return s.unencodable(ScrubSectionSubscriber)
+	}
+
+	switch r := generic.(type) {
+	case map[string]interface{}:
+		return r, s.subscriber(r, reflect.ValueOf(to))
+	case []interface{}:
+		changed := false
+		toValue := reflect.Indirect(reflect.ValueOf(to))
+		for i, recipient := range r {
+			if m, ok := recipient.(map[string]interface{}); ok {
+				original := reflect.Value{}
+				if toValue.Kind() == reflect.Slice {
+					original = toValue.Index(i)
+				}
+				changed = s.subscriber(m, original) || changed
+			}
+		}
+		return r, changed
+	}
+
+	return to, false
+}
+
+// subscriber scrubs the data of an inline subscriber. original is the value the
+// recipient was decoded from, used to find tagged fields in its data.
+func (s *scrubber) subscriber(recipient map[string]interface{}, original reflect.Value) bool {
+	data, ok := recipient["data"]
+	if !ok {
+		return false
+	}
+
+	tagged := make(map[string]bool)
+	if original.IsValid() {
+		collectTaggedPaths(original, []string{ScrubSectionSubscriber}, tagged)
+	}
+	s.tagged = make(map[string]bool, len(tagged))
+	prefix := ScrubSectionSubscriber + ".data."
+	for path := range tagged {
+		if strings.HasPrefix(path, prefix) {
+			s.tagged[ScrubSectionSubscriber+"."+strings.TrimPrefix(path, prefix)] = true
+		}
+	}
+
+	scrubbed, changed := s.walk(data, []string{ScrubSectionSubscriber})
+	recipient["data"] = scrubbed
+
+	return changed
+}
+
+func (s *scrubber) walk(v interface{}, path []string) (interface{}, bool) {
+	joined := strings.Join(path, ".")
+	if s.tagged[joined] {
+		return s.violate(joined, "tagged field")
+	}
+	for _, pattern := range s.policy.Paths {
+		if matchScrubPath(strings.Split(pattern, "."), path) {
+			return s.violate(joined, "path "+pattern)
+		}
+	}
+
+	switch value := v.(type) {
+	case map[string]interface{}:
+		changed := false
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			var c bool
+			value[k], c = s.walk(value[k], append(path, k))
+			changed = changed || c
+		}
+		return value, changed
+	case []interface{}:
+		changed := false
+		for i := range value {
+			var c bool
+			value[i], c = s.walk(value[i], append(path, strconv.Itoa(i)))
+			changed = changed || c
+		}
+		return value, changed
+	case string:
+		masked := value
+		for _, re := range s.policy.Patterns {
+			if re.MatchString(masked) {
+				s.violations = append(s.violations, ScrubViolation{Path: joined, Reason: "pattern " + re.String()})
+				masked = re.ReplaceAllString(masked, s.mask())
+			}
+		}
+		return masked, masked != value
+	}
+
+	return v, false
+}
+
+// unencodable drops a section that cannot be checked.
+func (s *scrubber) unencodable(root string) (interface{}, bool) {
+	s.violations = append(s.violations, ScrubViolation{Path: root, Reason: "not encodable as JSON"})
+
+	return nil, true
+}
+
+func (s *scrubber) violate(path string, reason string) (interface{}, bool) {
+	s.violations = append(s.violations, ScrubViolation{Path: path, Reason: reason})
+
+	return s.mask(), true
+}
+
+func matchScrubPath(pattern []string, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(path); i++ {
+			if matchScrubPath(pattern[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(path) == 0 || (pattern[0] != "*" && pattern[0] != path[0]) {
+		return false
+	}
+
+	return matchScrubPath(pattern[1:], path[1:])
+}
+
+// collectTaggedPaths records the JSON path of every struct field tagged as
+// sensitive found in v.
+func collectTaggedPaths(v reflect.Value, path []string, tagged map[string]bool) {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				if field.Anonymous {
+					collectTaggedPaths(v.Field(i), path, tagged)
+					continue
+				}
+				name = field.Name
+			}
+
+			fieldPath := append(append([]string{}, path...), name)
+			if field.Tag.Get(ScrubTagKey) == ScrubTagValue {
+				tagged[strings.Join(fieldPath, ".")] = true
+				continue
+			}
+			collectTaggedPaths(v.Field(i), fieldPath, tagged)
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			collectTaggedPaths(iter.Value(), append(append([]string{}, path...), iter.Key().String()), tagged)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			collectTaggedPaths(v.Index(i), append(append([]string{}, path...), strconv.Itoa(i)), tagged)
+		}
+	}
+}
+
+// toGeneric converts v into its JSON representation made of maps, slices and
+// scalars.
+func toGeneric(v interface{}) (interface{}, bool) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, false
+	}
+
+	var generic interface{}
+	if err := json.Unmarshal(b, &generic); err != nil {
+		return nil, false
+	}
+
+	return generic, true
+}
@@ -0,0 +1,189 @@
+package lib_test
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/saeid-a/go-novu/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type scrubTestPayment struct {
+	Amount     int    `json:"amount"`
+	CardNumber string `json:"cardNumber" novu:"pii"`
+}
+
+type scrubTestPayload struct {
+	Name    string           `json:"name"`
+	Payment scrubTestPayment `json:"payment"`
+	Notes   string           `json:"notes"`
+}
+
+var cardPattern = regexp.MustCompile(`\b\d{4}(?:[ -]?\d{4}){3}\b`)
+
+func TestScrubPolicy_Apply_Mask(t *testing.T) {
+	policy := lib.ScrubPolicy{
+		Mode:     lib.ScrubMask,
+		Paths:    []string{"payload.notes", "overrides.*.ssn", "subscriber.**.ssn"},
+		Patterns: []*regexp.Regexp{cardPattern},
+	}
+
+	data := lib.ITriggerPayloadOptions{
+		To: []lib.SubscriberPayload{
+			{SubscriberId: "alice", Data: map[string]interface{}{"profile": map[string]interface{}{"ssn": "123-45-6789"}}},
+			{SubscriberId: "bob"},
+		},
+		Payload: scrubTestPayload{
+			Name:    "Invoice paid with 4111 1111 1111 1111",
+			Payment: scrubTestPayment{Amount: 10, CardNumber: "4111111111111111"},
+			Notes:   "internal only",
+		},
+		Overrides: map[string]interface{}{"email": map[string]interface{}{"ssn": "123-45-6789", "replyTo": "a@b.c"}},
+	}
+
+	violations, err := policy.Apply(&data)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{
+		"payload.payment.cardNumber",
+		"payload.name",
+		"payload.notes",
+		"overrides.email.ssn",
+		"subscriber.profile.ssn",
+	}, violationPaths(violations))
+
+	assert.Equal(t, map[string]interface{}{
+		"name":    "Invoice paid with [REDACTED]",
+		"payment": map[string]interface{}{"amount": float64(10), "cardNumber": "[REDACTED]"},
+		"notes":   "[REDACTED]",
+	}, data.Payload)
+	assert.Equal(t, map[string]interface{}{
+		"email": map[string]interface{}{"ssn": "[REDACTED]", "replyTo": "a@b.c"},
+	}, data.Overrides)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"subscriberId": "alice", "data": map[string]interface{}{"profile": map[string]interface{}{"ssn": "[REDACTED]"}}},
+		map[string]interface{}{"subscriberId": "bob"},
+	}, data.To)
+}
+
+func TestScrubPolicy_Apply_Untouched(t *testing.T) {
+	policy := lib.ScrubPolicy{Paths: []string{"payload.notes"}}
+
+	to := []string{"alice", "bob"}
+	payload := map[string]interface{}{"name": "John"}
+	data := lib.ITriggerPayloadOptions{To: to, Payload: payload}
+
+	violations, err := policy.Apply(&data)
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+	assert.Equal(t, to, data.To)
+	assert.Equal(t, payload, data.Payload)
+}
+
+func TestEventServiceTrigger_ScrubPolicy(t *testing.T) {
+	var (
+		receivedBody lib.EventRequest
+		requests     int
+	)
+
+	eventService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		if err := json.NewDecoder(req.Body).Decode(&receivedBody); err != nil {
+			log.Printf("error in unmarshalling %+v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"data":{"acknowledged":true,"status":"processed"}}`))
+	}))
+	defer eventService.Close()
+
+	ctx := context.Background()
+	payload := scrubTestPayload{Name: "John", Payment: scrubTestPayment{CardNumber: "4111111111111111"}}
+
+	t.Run("Sensitive values are masked", func(t *testing.T) {
+		c := lib.NewAPIClient(novuApiKey, &lib.Config{
+			BackendURL:  lib.MustParseURL(eventService.URL),
+			ScrubPolicy: &lib.ScrubPolicy{Mode: lib.ScrubMask, Mask: "***"},
+		})
+
+		_, err := c.EventApi.Trigger(ctx, novuEventId, lib.ITriggerPayloadOptions{To: subscriberID, Payload: payload})
+		require.NoError(t, err)
+		assert.Equal(t, 1, requests)
+		assert.Equal(t, "***", receivedBody.Payload.(map[string]interface{})["payment"].(map[string]interface{})["cardNumber"])
+	})
+
+	t.Run("Sensitive values are rejected", func(t *testing.T) {
+		c := lib.NewAPIClient(novuApiKey, &lib.Config{
+			BackendURL:  lib.MustParseURL(eventService.URL),
+			ScrubPolicy: &lib.ScrubPolicy{Mode: lib.ScrubReject},
+		})
+
+		_, err := c.EventApi.Trigger(ctx, novuEventId, lib.ITriggerPayloadOptions{To: subscriberID, Payload: payload})
+		var scrubErr *lib.ScrubError
+		require.ErrorAs(t, err, &scrubErr)
+		assert.Equal(t, []string{"payload.payment.cardNumber"}, violationPaths(scrubErr.Violations))
+		assert.Equal(t, 1, requests)
+	})
+}
+
+func violationPaths(violations []lib.ScrubViolation) []string {
+	paths := make([]string, 0, len(violations))
+	for _, v := range violations {
+		paths = append(paths, v.Path)
+	}
+	return paths
+}
+
+func TestScrubPolicy_Apply_Unencodable(t *testing.T) {
+	payload := map[string]interface{}{"ssn": "123-45-6789", "callback": func() {}}
+
+	data := lib.ITriggerPayloadOptions{To: "alice", Payload: payload}
+	_, err := (&lib.ScrubPolicy{Mode: lib.ScrubReject}).Apply(&data)
+	var scrubErr *lib.ScrubError
+	require.ErrorAs(t, err, &scrubErr, "a payload that cannot be checked is rejected")
+	assert.Equal(t, []string{"payload"}, violationPaths(scrubErr.Violations))
+
+	data = lib.ITriggerPayloadOptions{To: "alice", Payload: payload}
+	violations, err := (&lib.ScrubPolicy{Mode: lib.ScrubMask}).Apply(&data)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"payload"}, violationPaths(violations))
+	assert.Nil(t, data.Payload, "a payload that cannot be checked is dropped")
+	assert.Equal(t, "alice", data.To)
+}
+
+func TestEventServiceTrigger_ScrubPolicy_FanOut(t *testing.T) {
+	var notes []string
+	eventService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body lib.BulkTriggerEvent
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+		for _, event := range body.Events {
+			notes = append(notes, event.Payload.(map[string]interface{})["notes"].(string))
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`[{"data":{"acknowledged":true,"status":"processed"}},{"data":{"acknowledged":true,"status":"processed"}}]`))
+	}))
+	defer eventService.Close()
+
+	// The mask matches the pattern, so a second scrub would mask it again.
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{
+		BackendURL:           lib.MustParseURL(eventService.URL),
+		MaxTriggerRecipients: 1,
+		ScrubPolicy:          &lib.ScrubPolicy{Patterns: []*regexp.Regexp{regexp.MustCompile(`#+`)}, Mask: "<#>"},
+	})
+
+	_, err := c.EventApi.Trigger(context.Background(), novuEventId, lib.ITriggerPayloadOptions{
+		To:      []string{"alice", "bob"},
+		Payload: map[string]interface{}{"notes": "pin ####"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pin <#>", "pin <#>"}, notes)
+}
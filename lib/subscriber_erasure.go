@@ -0,0 +1,301 @@
+package lib
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type ErasureStep string
+
+const (
+	ErasureMessages    ErasureStep = "messages"
+	ErasureTopics      ErasureStep = "topics"
+	ErasureCredentials ErasureStep = "credentials"
+	ErasureSubscriber  ErasureStep = "subscriber"
+)
+
+// ErasureSteps are the steps of EraseSubscriber, in the order they run. The
+// subscriber is deleted last so that an interrupted erasure can still find
+// what is left of it.
+var ErasureSteps = []ErasureStep{ErasureMessages, ErasureTopics, ErasureCredentials, ErasureSubscriber}
+
+type ErasureStatus string
+
+const (
+	ErasurePending ErasureStatus = "pending"
+	ErasureDone    ErasureStatus = "done"
+	ErasureFailed  ErasureStatus = "failed"
+)
+
+// SubscriberErasureOptions configures SubscriberService.EraseSubscriber.
+type SubscriberErasureOptions struct {
+	// PageSize is the number of messages listed at once. Defaults to
+	// DefaultSubscribersPageSize.
+	PageSize int
+
+	// Resume continues the erasure recorded in the report of an interrupted
+	// run: the steps already done are skipped and the others are retried.
+	Resume *SubscriberErasureReport
+
+	// Checkpoint, when set, is called with the report every time it changes,
+	// so that it can be persisted and passed back as Resume. Returning an
+	// error stops the erasure.
+	Checkpoint func(SubscriberErasureReport) error
+}
+
+// SubscriberErasureStepReport records what a step removed. Removed holds the
+// message ids, topic keys or provider ids depending on the step.
+type SubscriberErasureStepReport struct {
+	Step        ErasureStep   `json:"step"`
+	Status      ErasureStatus `json:"status"`
+	Removed     []string      `json:"removed,omitempty"`
+	Error       string        `json:"error,omitempty"`
+	StartedAt   *time.Time    `json:"startedAt,omitempty"`
+	CompletedAt *time.Time    `json:"completedAt,omitempty"`
+}
+
+// SubscriberErasureReport is the audit trail of the erasure of a subscriber.
+type SubscriberErasureReport struct {
+	SubscriberId string                        `json:"subscriberId"`
+	StartedAt    time.Time                     `json:"startedAt"`
+	CompletedAt  *time.Time                    `json:"completedAt,omitempty"`
+	Steps        []SubscriberErasureStepReport `json:"steps"`
+}
+
+// Completed reports whether every step is done.
+func (r *SubscriberErasureReport) Completed() bool {
+	return r.CompletedAt != nil
+}
+
+// Step returns the report of a step, nil when the report has no such step.
+func (r *SubscriberErasureReport) Step(step ErasureStep) *SubscriberErasureStepReport {
+	for i := range r.Steps {
+		if r.Steps[i].Step == step {
+			return &r.Steps[i]
+		}
+	}
+	return nil
+}
+
+func newSubscriberErasureReport(subscriberID string) *SubscriberErasureReport {
+	report := &SubscriberErasureReport{SubscriberId: subscriberID, StartedAt: time.Now().UTC()}
+	for _, step := range ErasureSteps {
+		report.Steps = append(report.Steps, SubscriberErasureStepReport{Step: step, Status: ErasurePending})
+	}
+	return report
+}
+
+// EraseSubscriber removes every trace of a subscriber: its messages, its topic
+// memberships, its channel credentials and finally the subscriber itself.
+//
+// The erasure stops at the first failure and returns the report along with
+// the error. Every step only removes what is still there, so an interrupted
+// erasure is resumed by calling EraseSubscriber again with the last report as
+// opts.Resume.
+func (s *SubscriberService) EraseSubscriber(ctx context.Context, subscriberID string, opts *SubscriberErasureOptions) (*SubscriberErasureReport, error) {
+	if opts == nil {
+		opts = &SubscriberErasureOptions{}
+	}
+	pageSize := opts.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultSubscribersPageSize
+	}
+
+	report := newSubscriberErasureReport(subscriberID)
+	if opts.Resume != nil {
+		if opts.Resume.SubscriberId != subscriberID {
+			return nil, errors.Errorf("cannot resume the erasure of %s for %s", opts.Resume.SubscriberId, subscriberID)
+		}
+		resumed := *opts.Resume
+		resumed.Steps = append([]SubscriberErasureStepReport(nil), opts.Resume.Steps...)
+		for i := range resumed.Steps {
+			resumed.Steps[i].Removed = append([]string(nil), resumed.Steps[i].Removed...)
+		}
+		for _, step := range ErasureSteps {
+			if resumed.Step(step) == nil {
+				resumed.Steps = append(resumed.Steps, SubscriberErasureStepReport{Step: step, Status: ErasurePending})
+			}
+		}
+		report = &resumed
+	}
+
+	checkpoint := func() error {
+		if opts.Checkpoint == nil {
+			return nil
+		}
+		return opts.Checkpoint(*report)
+	}
+
+	for _, name := range ErasureSteps {
+		step := report.Step(name)
+		if step.Status == ErasureDone {
+			continue
+		}
+
+		now := time.Now().UTC()
+		if step.StartedAt == nil {
+			step.StartedAt = &now
+		}
+		step.Status, step.Error = ErasurePending, ""
+
+		if err := s.eraseStep(ctx, subscriberID, pageSize, step, checkpoint); err != nil {
+			step.Status, step.Error = ErasureFailed, err.Error()
+			if cerr := checkpoint(); cerr != nil {
+				return report, cerr
+			}
+			return report, errors.Wrapf(err, "unable to erase %s of %s", name, subscriberID)
+		}
+
+		now = time.Now().UTC()
+		step.Status, step.CompletedAt = ErasureDone, &now
+		if err := checkpoint(); err != nil {
+			return report, err
+		}
+	}
+
+	now := time.Now().UTC()
+	report.CompletedAt = &now
+	if err := checkpoint(); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+func (s *SubscriberService) eraseStep(ctx context.Context, subscriberID string, pageSize int, step *SubscriberErasureStepReport, checkpoint func() error) error {
+	switch step.Step {
+	case ErasureMessages:
+		return s.eraseMessages(ctx, subscriberID, pageSize, step, checkpoint)
+	case ErasureTopics:
+		return s.eraseTopics(ctx, subscriberID, step, checkpoint)
+	case ErasureCredentials:
+		return s.eraseCredentials(ctx, subscriberID, step, checkpoint)
+	case ErasureSubscriber:
+		return s.eraseSubscriber(ctx, subscriberID, step)
+	}
+
+	return errors.Errorf("unknown erasure step %s", step.Step)
+}
+
+// eraseMessages deletes the messages of the subscriber, always listing the
+// first page as the previous one is gone.
+func (s *SubscriberService) eraseMessages(ctx context.Context, subscriberID string, pageSize int, step *SubscriberErasureStepReport, checkpoint func() error) error {
+	deleted := make(map[string]bool)
+
+	for {
+		resp, err := s.client.MessagesApi.GetMessages(ctx, MessagesQueryParams{SubscriberId: subscriberID, Limit: pageSize})
+		if err != nil {
+			return errors.Wrap(err, "unable to list messages")
+		}
+
+		var messages []struct {
+			Id string `json:"_id"`
+		}
+		if err := decodeJsonResponse(resp, &messages); err != nil {
+			return errors.Wrap(err, "unable to decode messages")
+		}
+
+		fresh := 0
+		for _, message := range messages {
+			if deleted[message.Id] {
+				continue
+			}
+			fresh++
+
+			if _, err := s.client.MessagesApi.DeleteMessage(ctx, message.Id); err != nil {
+				return errors.Wrapf(err, "unable to delete message %s", message.Id)
+			}
+			deleted[message.Id] = true
+			step.Removed = append(step.Removed, message.Id)
+		}
+
+		// A page of messages already deleted means the listing lags behind
+		// the deletions, there is nothing left to delete.
+		if fresh == 0 {
+			return nil
+		}
+		if err := checkpoint(); err != nil {
+			return err
+		}
+		if len(messages) < pageSize {
+			return nil
+		}
+	}
+}
+
+func (s *SubscriberService) eraseTopics(ctx context.Context, subscriberID string, step *SubscriberErasureStepReport, checkpoint func() error) error {
+	memberships, err := s.topicMemberships(ctx)
+	if err != nil {
+		return errors.Wrap(err, "unable to list topics")
+	}
+
+	for _, key := range memberships[subscriberID] {
+		if err := s.client.TopicsApi.RemoveSubscribers(ctx, key, []string{subscriberID}); err != nil {
+			return errors.Wrapf(err, "unable to remove from topic %s", key)
+		}
+		step.Removed = append(step.Removed, key)
+		if err := checkpoint(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// eraseCredentials deletes the channel credentials of the subscriber. A
+// subscriber that is already gone has no credentials left.
+func (s *SubscriberService) eraseCredentials(ctx context.Context, subscriberID string, step *SubscriberErasureStepReport, checkpoint func() error) error {
+	var subscriber SubscriberResponse
+	URL := s.client.config.BackendURL.JoinPath("subscribers", subscriberID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, URL.String(), http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.client.sendRequest(req, &subscriber)
+	if isNotFound(res) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "unable to get subscriber")
+	}
+
+	for _, channel := range subscriber.Data.Channels {
+		if err := s.DeleteCredentials(ctx, subscriberID, channel.ProviderId); err != nil {
+			return errors.Wrapf(err, "unable to delete %s credentials", channel.ProviderId)
+		}
+		step.Removed = append(step.Removed, string(channel.ProviderId))
+		if err := checkpoint(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// eraseSubscriber deletes the subscriber. A subscriber that is already gone
+// was deleted by a run interrupted before its report was saved.
+func (s *SubscriberService) eraseSubscriber(ctx context.Context, subscriberID string, step *SubscriberErasureStepReport) error {
+	URL := s.client.config.BackendURL.JoinPath("subscribers", subscriberID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, URL.String(), http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.client.sendRequest(req, &DeleteSubscriberResponse{})
+	if err != nil && !isNotFound(res) {
+		return err
+	}
+	step.Removed = append(step.Removed, subscriberID)
+
+	return nil
+}
+
+func isNotFound(res *http.Response) bool {
+	return res != nil && res.StatusCode == http.StatusNotFound
+}
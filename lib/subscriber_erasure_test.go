@@ -0,0 +1,259 @@
+package lib_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/saeid-a/go-novu/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// erasureState holds the messages, topics and credentials of alice. failTopic
+// makes the removal from that topic fail.
+type erasureState struct {
+	messages    []string
+	topics      map[string][]string
+	credentials []lib.ProviderIdType
+	deleted     bool
+	failTopic   string
+	listed      int
+}
+
+func newErasureState() *erasureState {
+	return &erasureState{
+		messages:    []string{"m1", "m2", "m3"},
+		topics:      map[string][]string{"news": {"alice", "bob"}, "sport": {"bob"}, "tech": {"alice"}},
+		credentials: []lib.ProviderIdType{lib.ProviderFCM, lib.ProviderSlack},
+	}
+}
+
+func erasureServer(t *testing.T, state *erasureState) *httptest.Server {
+	var mu sync.Mutex
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		path := strings.TrimPrefix(req.URL.Path, "/v1/")
+		if state.deleted && strings.HasPrefix(path, "subscribers/alice") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		switch {
+		case path == "messages":
+			state.listed++
+			if req.URL.Query().Get("subscriberId") != "alice" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			limit, _ := strconv.Atoi(req.URL.Query().Get("limit"))
+			page := state.messages
+			if len(page) > limit {
+				page = page[:limit]
+			}
+			var data []map[string]string
+			for _, id := range page {
+				data = append(data, map[string]string{"_id": id})
+			}
+			bb, _ := json.Marshal(map[string]interface{}{"data": data})
+			w.Write(bb)
+		case strings.HasPrefix(path, "messages/"):
+			id := strings.TrimPrefix(path, "messages/")
+			for i, m := range state.messages {
+				if m == id {
+					state.messages = append(state.messages[:i], state.messages[i+1:]...)
+					break
+				}
+			}
+			w.Write([]byte(`{"data": {"acknowledged": true}}`))
+		case path == "topics":
+			var topics []lib.GetTopicResponse
+			for key, subscribers := range state.topics {
+				topics = append(topics, lib.GetTopicResponse{Key: key, Subscribers: subscribers})
+			}
+			sort.Slice(topics, func(i, j int) bool { return topics[i].Key < topics[j].Key })
+			bb, _ := json.Marshal(listTopicsPage(req, topics))
+			w.Write(bb)
+		case strings.HasSuffix(path, "/subscribers/removal"):
+			key := strings.TrimSuffix(strings.TrimPrefix(path, "topics/"), "/subscribers/removal")
+			if key == state.failTopic {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			var body lib.SubscribersTopicRequest
+			_ = json.NewDecoder(req.Body).Decode(&body)
+			var kept []string
+			for _, subscriberId := range state.topics[key] {
+				if subscriberId != body.Subscribers[0] {
+					kept = append(kept, subscriberId)
+				}
+			}
+			state.topics[key] = kept
+			w.WriteHeader(http.StatusNoContent)
+		case strings.HasPrefix(path, "subscribers/alice/credentials/"):
+			provider := lib.ProviderIdType(strings.TrimPrefix(path, "subscribers/alice/credentials/"))
+			for i, p := range state.credentials {
+				if p == provider {
+					state.credentials = append(state.credentials[:i], state.credentials[i+1:]...)
+					break
+				}
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case path == "subscribers/alice" && req.Method == http.MethodDelete:
+			state.deleted = true
+			w.Write([]byte(`{"data": {"acknowledged": true, "status": "deleted"}}`))
+		case path == "subscribers/alice":
+			subscriber := lib.Subscriber{SubscriberId: "alice"}
+			for _, provider := range state.credentials {
+				subscriber.Channels = append(subscriber.Channels, lib.SubscriberChannel{ProviderId: provider})
+			}
+			bb, _ := json.Marshal(lib.SubscriberResponse{Data: subscriber})
+			w.Write(bb)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestSubscriberService_EraseSubscriber(t *testing.T) {
+	state := newErasureState()
+	httpServer := erasureServer(t, state)
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+
+	var checkpoints int
+	report, err := c.SubscriberApi.EraseSubscriber(context.Background(), "alice", &lib.SubscriberErasureOptions{
+		PageSize:   2,
+		Checkpoint: func(lib.SubscriberErasureReport) error { checkpoints++; return nil },
+	})
+	require.NoError(t, err)
+
+	assert.True(t, report.Completed())
+	assert.Equal(t, []string{"m1", "m2", "m3"}, report.Step(lib.ErasureMessages).Removed)
+	assert.Equal(t, []string{"news", "tech"}, report.Step(lib.ErasureTopics).Removed)
+	assert.Equal(t, []string{"fcm", "slack"}, report.Step(lib.ErasureCredentials).Removed)
+	assert.Equal(t, []string{"alice"}, report.Step(lib.ErasureSubscriber).Removed)
+	for _, step := range report.Steps {
+		assert.Equal(t, lib.ErasureDone, step.Status, step.Step)
+		assert.NotNil(t, step.StartedAt)
+		assert.NotNil(t, step.CompletedAt)
+	}
+	assert.Greater(t, checkpoints, len(lib.ErasureSteps))
+
+	assert.Empty(t, state.messages)
+	assert.Equal(t, []string{"bob"}, state.topics["news"])
+	assert.Empty(t, state.credentials)
+	assert.True(t, state.deleted)
+}
+
+func TestSubscriberService_EraseSubscriber_Resume(t *testing.T) {
+	state := newErasureState()
+	httpServer := erasureServer(t, state)
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+	state.failTopic = "tech"
+	ctx := context.Background()
+
+	report, err := c.SubscriberApi.EraseSubscriber(ctx, "alice", nil)
+	require.Error(t, err)
+
+	assert.False(t, report.Completed())
+	assert.Equal(t, lib.ErasureDone, report.Step(lib.ErasureMessages).Status)
+	topics := report.Step(lib.ErasureTopics)
+	assert.Equal(t, lib.ErasureFailed, topics.Status)
+	assert.Equal(t, []string{"news"}, topics.Removed)
+	assert.NotEmpty(t, topics.Error)
+	assert.Equal(t, lib.ErasurePending, report.Step(lib.ErasureSubscriber).Status)
+	assert.False(t, state.deleted, "the subscriber is kept until everything else is erased")
+
+	// The report is persisted between the runs.
+	bb, err := json.Marshal(report)
+	require.NoError(t, err)
+	var saved lib.SubscriberErasureReport
+	require.NoError(t, json.Unmarshal(bb, &saved))
+
+	state.failTopic = ""
+	listed := state.listed
+	report, err = c.SubscriberApi.EraseSubscriber(ctx, "alice", &lib.SubscriberErasureOptions{Resume: &saved})
+	require.NoError(t, err)
+
+	assert.True(t, report.Completed())
+	assert.Equal(t, listed, state.listed, "messages are not listed again")
+	assert.Equal(t, []string{"news", "tech"}, report.Step(lib.ErasureTopics).Removed)
+	assert.Equal(t, saved.StartedAt, report.StartedAt)
+	assert.True(t, state.deleted)
+
+	_, err = c.SubscriberApi.EraseSubscriber(ctx, "bob", &lib.SubscriberErasureOptions{Resume: &saved})
+	assert.Error(t, err)
+}
+
+func TestSubscriberService_EraseSubscriber_ResumeDeleted(t *testing.T) {
+	for _, interrupted := range []lib.ErasureStep{lib.ErasureCredentials, lib.ErasureSubscriber} {
+		t.Run(string(interrupted), func(t *testing.T) {
+			state := newErasureState()
+			httpServer := erasureServer(t, state)
+			c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+			ctx := context.Background()
+
+			// The report is saved at every checkpoint until saving it fails
+			// right after the interrupted step.
+			var saved []byte
+			_, err := c.SubscriberApi.EraseSubscriber(ctx, "alice", &lib.SubscriberErasureOptions{
+				Checkpoint: func(report lib.SubscriberErasureReport) error {
+					if report.Step(interrupted).Status == lib.ErasureDone {
+						return fmt.Errorf("disk full")
+					}
+					saved, _ = json.Marshal(report)
+					return nil
+				},
+			})
+			require.Error(t, err)
+
+			// The subscriber is deleted before the erasure is resumed, by
+			// the interrupted run or by someone else.
+			state.deleted = true
+
+			var resume lib.SubscriberErasureReport
+			require.NoError(t, json.Unmarshal(saved, &resume))
+			assert.Equal(t, lib.ErasurePending, resume.Step(interrupted).Status)
+
+			report, err := c.SubscriberApi.EraseSubscriber(ctx, "alice", &lib.SubscriberErasureOptions{Resume: &resume})
+			require.NoError(t, err)
+
+			assert.True(t, report.Completed())
+			assert.Equal(t, []string{"alice"}, report.Step(lib.ErasureSubscriber).Removed)
+		})
+	}
+}
+
+func TestSubscriberService_EraseSubscriber_TopicPages(t *testing.T) {
+	state := newErasureState()
+	for i := 0; i < 2*lib.DefaultTopicsPageSize; i++ {
+		key := fmt.Sprintf("topic-%03d", i)
+		state.topics[key] = []string{"bob"}
+		if i%50 == 0 {
+			state.topics[key] = append(state.topics[key], "alice")
+		}
+	}
+	httpServer := erasureServer(t, state)
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+
+	report, err := c.SubscriberApi.EraseSubscriber(context.Background(), "alice", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"news", "tech", "topic-000", "topic-050", "topic-100", "topic-150"}, report.Step(lib.ErasureTopics).Removed)
+	for key, subscribers := range state.topics {
+		assert.NotContains(t, subscribers, "alice", key)
+	}
+}
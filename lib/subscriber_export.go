@@ -0,0 +1,262 @@
+package lib
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type ExportFormat int
+
+const (
+	ExportJSONL ExportFormat = iota
+	ExportCSV
+)
+
+// DefaultExportConcurrency is the number of preference requests in flight
+// during an export.
+const DefaultExportConcurrency = 4
+
+// SubscriberExportOptions configures SubscriberService.Export.
+type SubscriberExportOptions struct {
+	Format ExportFormat
+
+	// WithPreferences adds the workflow preferences of every subscriber.
+	WithPreferences bool
+	// WithTopics adds the keys of the topics every subscriber belongs to.
+	WithTopics bool
+
+	// PageSize defaults to DefaultSubscribersPageSize.
+	PageSize int
+	// Concurrency is the number of preference requests in flight. Defaults to
+	// DefaultExportConcurrency.
+	Concurrency int
+
+	// StartPage resumes an interrupted export from the NextPage of its last
+	// checkpoint. The CSV header is only written when starting from page 0.
+	StartPage int
+	// Checkpoint, when set, is called once a page has been fully written.
+	// Returning an error stops the export.
+	Checkpoint func(SubscriberExportCheckpoint) error
+}
+
+type SubscriberExportCheckpoint struct {
+	NextPage int `json:"nextPage"`
+	Exported int `json:"exported"`
+}
+
+// ExportedSubscriber is a line of a subscriber export.
+type ExportedSubscriber struct {
+	Subscriber
+	Preferences []SubscriberPreference `json:"preferences,omitempty"`
+	Topics      []string               `json:"topics,omitempty"`
+}
+
+var subscriberExportColumns = []string{
+	"subscriberId", "firstName", "lastName", "email", "phone", "avatar", "locale",
+	"data", "channels", "isOnline", "lastOnlineAt", "createdAt", "updatedAt",
+	"topics", "preferences",
+}
+
+// Export writes every subscriber of the environment to w, page by page. The
+// returned checkpoint tells where to resume when the export was interrupted.
+func (s *SubscriberService) Export(ctx context.Context, w io.Writer, opts SubscriberExportOptions) (SubscriberExportCheckpoint, error) {
+	if opts.PageSize <= 0 {
+		opts.PageSize = DefaultSubscribersPageSize
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultExportConcurrency
+	}
+
+	checkpoint := SubscriberExportCheckpoint{NextPage: opts.StartPage}
+
+	var topics map[string][]string
+	if opts.WithTopics {
+		var err error
+		if topics, err = s.topicMemberships(ctx); err != nil {
+			return checkpoint, err
+		}
+	}
+
+	writeRow := s.exportWriter(w, opts)
+	if opts.Format == ExportCSV && opts.StartPage == 0 {
+		if err := writeRow(nil); err != nil {
+			return checkpoint, err
+		}
+	}
+
+	for {
+		page := checkpoint.NextPage
+		resp, err := s.List(ctx, &ListSubscribersOptions{Page: &page, Limit: &opts.PageSize})
+		if err != nil {
+			return checkpoint, errors.Wrapf(err, "unable to list subscribers page %d", page)
+		}
+
+		rows := make([]ExportedSubscriber, len(resp.Data))
+		for i, subscriber := range resp.Data {
+			rows[i] = ExportedSubscriber{Subscriber: subscriber, Topics: topics[subscriber.SubscriberId]}
+		}
+		if opts.WithPreferences {
+			if err := s.exportPreferences(ctx, rows, opts.Concurrency); err != nil {
+				return checkpoint, err
+			}
+		}
+
+		for i := range rows {
+			if err := writeRow(&rows[i]); err != nil {
+				return checkpoint, err
+			}
+		}
+
+		checkpoint.NextPage++
+		checkpoint.Exported += len(rows)
+		if opts.Checkpoint != nil {
+			if err := opts.Checkpoint(checkpoint); err != nil {
+				return checkpoint, err
+			}
+		}
+
+		if len(resp.Data) == 0 || (!resp.HasMore && len(resp.Data) < opts.PageSize) {
+			return checkpoint, nil
+		}
+	}
+}
+
+// topicMemberships maps every subscriber id onto the keys of its topics.
+func (s *SubscriberService) topicMemberships(ctx context.Context) (map[string][]string, error) {
+	memberships := make(map[string][]string)
+	err := s.client.TopicsApi.ListAll(ctx, func(topic GetTopicResponse) error {
+		for _, subscriberId := range topic.Subscribers {
+			memberships[subscriberId] = append(memberships[subscriberId], topic.Key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, keys := range memberships {
+		sort.Strings(keys)
+	}
+
+	return memberships, nil
+}
+
+func (s *SubscriberService) exportPreferences(ctx context.Context, rows []ExportedSubscriber, concurrency int) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	sem := make(chan struct{}, concurrency)
+
+	for i := range rows {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(row *ExportedSubscriber) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			resp, err := s.GetPreferences(ctx, row.SubscriberId)
+			if err != nil {
+				once.Do(func() {
+					firstErr = errors.Wrapf(err, "unable to get preferences of %s", row.SubscriberId)
+					cancel()
+				})
+				return
+			}
+			row.Preferences = resp.Data
+		}(&rows[i])
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	return ctx.Err()
+}
+
+// exportWriter returns a function writing a row in the export format. A nil
+// row writes the CSV header.
+func (s *SubscriberService) exportWriter(w io.Writer, opts SubscriberExportOptions) func(*ExportedSubscriber) error {
+	if opts.Format == ExportCSV {
+		writer := csv.NewWriter(w)
+		return func(row *ExportedSubscriber) error {
+			record := subscriberExportColumns
+			if row != nil {
+				record = csvExportRecord(row)
+			}
+			if err := writer.Write(record); err != nil {
+				return errors.Wrap(err, "unable to write csv")
+			}
+			writer.Flush()
+			return writer.Error()
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	return func(row *ExportedSubscriber) error {
+		if row == nil {
+			return nil
+		}
+		return errors.Wrap(encoder.Encode(row), "unable to write jsonl")
+	}
+}
+
+func csvExportRecord(row *ExportedSubscriber) []string {
+	jsonColumn := func(v interface{}, empty bool) string {
+		if empty {
+			return ""
+		}
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	timeColumn := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339Nano)
+	}
+
+	lastOnlineAt := ""
+	if row.LastOnlineAt != nil {
+		lastOnlineAt = timeColumn(*row.LastOnlineAt)
+	}
+
+	return []string{
+		row.SubscriberId,
+		row.FirstName,
+		row.LastName,
+		row.Email,
+		row.Phone,
+		row.Avatar,
+		row.Locale,
+		jsonColumn(row.Data, len(row.Data) == 0),
+		jsonColumn(row.Channels, len(row.Channels) == 0),
+		strconv.FormatBool(row.IsOnline),
+		lastOnlineAt,
+		timeColumn(row.CreatedAt),
+		timeColumn(row.UpdatedAt),
+		strings.Join(row.Topics, ";"),
+		jsonColumn(row.Preferences, len(row.Preferences) == 0),
+	}
+}
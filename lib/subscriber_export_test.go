@@ -0,0 +1,137 @@
+package lib_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/saeid-a/go-novu/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exportServer(t *testing.T) *httptest.Server {
+	// The memberships of project are only on the second page of topics.
+	topics := []lib.GetTopicResponse{{Key: "admins", Subscribers: []string{"alice"}}}
+	for i := 1; i < lib.DefaultTopicsPageSize; i++ {
+		topics = append(topics, lib.GetTopicResponse{Key: "empty-" + strconv.Itoa(i)})
+	}
+	topics = append(topics, lib.GetTopicResponse{Key: "project", Subscribers: []string{"bob", "alice"}})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodGet, req.Method)
+
+		var resp interface{}
+		switch {
+		case req.URL.Path == "/v1/topics":
+			resp = listTopicsPage(req, topics)
+		case req.URL.Path == "/v1/subscribers":
+			page, _ := strconv.Atoi(req.URL.Query().Get("page"))
+			limit, _ := strconv.Atoi(req.URL.Query().Get("limit"))
+			start, end := page*limit, page*limit+limit
+			if start > len(listedSubscribers) {
+				start = len(listedSubscribers)
+			}
+			if end > len(listedSubscribers) {
+				end = len(listedSubscribers)
+			}
+			resp = lib.ListSubscribersResponse{Page: page, PageSize: limit, HasMore: end < len(listedSubscribers), Data: listedSubscribers[start:end]}
+		case strings.HasSuffix(req.URL.Path, "/preferences"):
+			id := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, "/v1/subscribers/"), "/preferences")
+			resp = lib.SubscriberPreferencesResponse{Data: []lib.SubscriberPreference{{
+				Template:   lib.Template{ID: "template-" + id, Name: "Digest"},
+				Preference: lib.Preference{Enabled: true},
+			}}}
+		default:
+			t.Errorf("unexpected request %s", req.URL)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		bb, _ := json.Marshal(resp)
+		w.Write(bb)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestSubscriberService_Export_JSONL(t *testing.T) {
+	server := exportServer(t)
+
+	var (
+		out         bytes.Buffer
+		checkpoints []lib.SubscriberExportCheckpoint
+	)
+	ctx := context.Background()
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(server.URL)})
+	checkpoint, err := c.SubscriberApi.Export(ctx, &out, lib.SubscriberExportOptions{
+		Format:          lib.ExportJSONL,
+		WithPreferences: true,
+		WithTopics:      true,
+		PageSize:        2,
+		Checkpoint: func(c lib.SubscriberExportCheckpoint) error {
+			checkpoints = append(checkpoints, c)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, lib.SubscriberExportCheckpoint{NextPage: 2, Exported: 3}, checkpoint)
+	assert.Equal(t, []lib.SubscriberExportCheckpoint{{NextPage: 1, Exported: 2}, {NextPage: 2, Exported: 3}}, checkpoints)
+
+	var rows []lib.ExportedSubscriber
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var row lib.ExportedSubscriber
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &row))
+		rows = append(rows, row)
+	}
+
+	require.Len(t, rows, 3)
+	assert.Equal(t, "alice", rows[0].SubscriberId)
+	assert.Equal(t, []string{"admins", "project"}, rows[0].Topics)
+	assert.Equal(t, []string{"project"}, rows[1].Topics)
+	assert.Empty(t, rows[2].Topics)
+	require.Len(t, rows[2].Preferences, 1)
+	assert.Equal(t, "template-carol", rows[2].Preferences[0].Template.ID)
+}
+
+func TestSubscriberService_Export_CSV_Resume(t *testing.T) {
+	server := exportServer(t)
+	ctx := context.Background()
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(server.URL)})
+
+	var out bytes.Buffer
+	interrupted := errors.New("interrupted")
+	checkpoint, err := c.SubscriberApi.Export(ctx, &out, lib.SubscriberExportOptions{
+		Format:   lib.ExportCSV,
+		PageSize: 2,
+		Checkpoint: func(c lib.SubscriberExportCheckpoint) error {
+			return interrupted
+		},
+	})
+	require.ErrorIs(t, err, interrupted)
+	assert.Equal(t, lib.SubscriberExportCheckpoint{NextPage: 1, Exported: 2}, checkpoint)
+
+	_, err = c.SubscriberApi.Export(ctx, &out, lib.SubscriberExportOptions{
+		Format:    lib.ExportCSV,
+		PageSize:  2,
+		StartPage: checkpoint.NextPage,
+	})
+	require.NoError(t, err)
+
+	records, err := csv.NewReader(&out).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	assert.Equal(t, "subscriberId", records[0][0])
+	assert.Equal(t, []string{"alice", "bob", "carol"}, []string{records[1][0], records[2][0], records[3][0]})
+	assert.Equal(t, "alice@example.com", records[1][3])
+}
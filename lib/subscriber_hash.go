@@ -0,0 +1,88 @@
+package lib
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// ErrUnauthenticated is returned by a SubscriberAuthFunc when the request
+// carries no user. Any other error is answered as an internal error.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// SubscriberHash returns the HMAC-SHA256 of the subscriber id keyed with the
+// API key, which the in-app Inbox expects as subscriberHash when HMAC
+// encryption is enabled. It must only be computed server side.
+func (c APIClient) SubscriberHash(subscriberID string) string {
+	mac := hmac.New(sha256.New, []byte(c.apiKey))
+	mac.Write([]byte(subscriberID))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SubscriberAuthFunc returns the subscriber id of the user authenticated by
+// the request.
+type SubscriberAuthFunc func(r *http.Request) (subscriberID string, err error)
+
+type SubscriberCredentialsResponse struct {
+	SubscriberId          string `json:"subscriberId"`
+	SubscriberHash        string `json:"subscriberHash"`
+	ApplicationIdentifier string `json:"applicationIdentifier"`
+}
+
+// SubscriberAuthHandler serves the Inbox credentials of the authenticated
+// user, so that the frontend never sees the API key.
+type SubscriberAuthHandler struct {
+	client                *APIClient
+	applicationIdentifier string
+	authenticate          SubscriberAuthFunc
+}
+
+func NewSubscriberAuthHandler(client *APIClient, applicationIdentifier string, authenticate SubscriberAuthFunc) *SubscriberAuthHandler {
+	return &SubscriberAuthHandler{
+		client:                client,
+		applicationIdentifier: applicationIdentifier,
+		authenticate:          authenticate,
+	}
+}
+
+func (h *SubscriberAuthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	subscriberID, ok := authenticateSubscriber(w, r, h.authenticate)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(SubscriberCredentialsResponse{
+		SubscriberId:          subscriberID,
+		SubscriberHash:        h.client.SubscriberHash(subscriberID),
+		ApplicationIdentifier: h.applicationIdentifier,
+	})
+}
+
+// authenticateSubscriber only lets authenticated GET requests through and
+// writes the error response of the others.
+func authenticateSubscriber(w http.ResponseWriter, r *http.Request, authenticate SubscriberAuthFunc) (string, bool) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return "", false
+	}
+
+	subscriberID, err := authenticate(r)
+	if err != nil && !errors.Is(err, ErrUnauthenticated) {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return "", false
+	}
+	if err != nil || subscriberID == "" {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return "", false
+	}
+
+	return subscriberID, true
+}
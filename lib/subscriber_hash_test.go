@@ -0,0 +1,65 @@
+package lib_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/saeid-a/go-novu/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIClient_SubscriberHash(t *testing.T) {
+	c := lib.NewAPIClient("secret", &lib.Config{})
+
+	// echo -n "subscriber" | openssl dgst -sha256 -hmac "secret"
+	assert.Equal(t, "3328e3d3cc9f79344bbc225edb1c89a27398654fa1dd35b81e392c2744433ed4", c.SubscriberHash("subscriber"))
+}
+
+func TestSubscriberAuthHandler(t *testing.T) {
+	c := lib.NewAPIClient("secret", &lib.Config{})
+	handler := lib.NewSubscriberAuthHandler(c, "app-id", func(r *http.Request) (string, error) {
+		switch r.Header.Get("Authorization") {
+		case "Bearer alice":
+			return "subscriber", nil
+		case "Bearer broken":
+			return "", errors.New("session store down")
+		}
+		return "", lib.ErrUnauthenticated
+	})
+
+	tests := map[string]struct {
+		method string
+		auth   string
+		status int
+	}{
+		"authenticated":   {http.MethodGet, "Bearer alice", http.StatusOK},
+		"unauthenticated": {http.MethodGet, "", http.StatusUnauthorized},
+		"auth failure":    {http.MethodGet, "Bearer broken", http.StatusInternalServerError},
+		"wrong method":    {http.MethodPost, "Bearer alice", http.StatusMethodNotAllowed},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/novu/credentials", nil)
+			req.Header.Set("Authorization", tc.auth)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+			require.Equal(t, tc.status, rec.Code)
+
+			if tc.status == http.StatusOK {
+				var resp lib.SubscriberCredentialsResponse
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+				assert.Equal(t, lib.SubscriberCredentialsResponse{
+					SubscriberId:          "subscriber",
+					SubscriberHash:        c.SubscriberHash("subscriber"),
+					ApplicationIdentifier: "app-id",
+				}, resp)
+			}
+		})
+	}
+}
@@ -0,0 +1,329 @@
+package lib
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+type ImportFormat int
+
+const (
+	ImportCSV ImportFormat = iota
+	ImportJSONL
+)
+
+// SubscriberImportOptions configures SubscriberService.Import.
+type SubscriberImportOptions struct {
+	Format ImportFormat
+
+	// Columns maps CSV header names onto subscriber fields: "subscriberId",
+	// "firstName", "lastName", "email", "phone", "avatar", "locale" or
+	// "data.<key>" for custom data. Headers missing from the map are ignored.
+	// When nil the headers are used as field names.
+	Columns map[string]string
+
+	// ChunkSize is the number of subscribers per BulkCreate call. Defaults to
+	// and is capped at MaxBulkSubscribers.
+	ChunkSize int
+
+	// Concurrency is the number of BulkCreate calls in flight. Defaults to 1.
+	Concurrency int
+
+	// Progress, when set, is called after every chunk.
+	Progress func(SubscriberImportProgress)
+}
+
+type SubscriberImportProgress struct {
+	Read    int
+	Sent    int
+	Created int
+	Updated int
+	Failed  int
+}
+
+// SubscriberImportReport lists the outcome of every imported subscriber. Rows
+// that could not be decoded and chunks whose request failed are reported in
+// Failed.
+type SubscriberImportReport struct {
+	Created []string
+	Updated []string
+	Failed  []SubscriberBulkFailure
+}
+
+// Import streams subscribers from r and creates or updates them in chunks
+// through BulkCreate. Only read errors and context cancellation abort the
+// import; every other failure ends up in the report.
+func (s *SubscriberService) Import(ctx context.Context, r io.Reader, opts SubscriberImportOptions) (*SubscriberImportReport, error) {
+	if opts.ChunkSize <= 0 || opts.ChunkSize > MaxBulkSubscribers {
+		opts.ChunkSize = MaxBulkSubscribers
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	im := &subscriberImport{opts: opts, report: &SubscriberImportReport{}}
+	chunks := make(chan []SubscriberPayload)
+
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range chunks {
+				resp, err := s.BulkCreate(ctx, SubscriberBulkPayload{Subscribers: chunk})
+				im.chunkDone(chunk, resp, err)
+			}
+		}()
+	}
+
+	readErr := im.read(ctx, r, chunks)
+	close(chunks)
+	wg.Wait()
+
+	if readErr != nil {
+		return im.report, readErr
+	}
+
+	return im.report, ctx.Err()
+}
+
+type subscriberImport struct {
+	opts SubscriberImportOptions
+
+	mu       sync.Mutex
+	report   *SubscriberImportReport
+	progress SubscriberImportProgress
+}
+
+// read decodes r and sends the subscribers to chunks.
+func (im *subscriberImport) read(ctx context.Context, r io.Reader, chunks chan<- []SubscriberPayload) error {
+	chunk := make([]SubscriberPayload, 0, im.opts.ChunkSize)
+	emit := func(subscriber SubscriberPayload) error {
+		chunk = append(chunk, subscriber)
+		if len(chunk) < im.opts.ChunkSize {
+			return nil
+		}
+
+		select {
+		case chunks <- chunk:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		chunk = make([]SubscriberPayload, 0, im.opts.ChunkSize)
+		return nil
+	}
+
+	var err error
+	switch im.opts.Format {
+	case ImportCSV:
+		err = im.readCSV(r, emit)
+	case ImportJSONL:
+		err = im.readJSONL(r, emit)
+	default:
+		err = errors.Errorf("unknown import format %d", im.opts.Format)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(chunk) > 0 {
+		select {
+		case chunks <- chunk:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func (im *subscriberImport) readCSV(r io.Reader, emit func(SubscriberPayload) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "unable to read csv header")
+	}
+
+	fields := make([]string, len(header))
+	for i, column := range header {
+		column = strings.TrimSpace(column)
+		if im.opts.Columns == nil {
+			fields[i] = column
+		} else {
+			fields[i] = im.opts.Columns[column]
+		}
+		if fields[i] != "" && !isSubscriberField(fields[i]) {
+			return errors.Errorf("csv column %q maps to unknown subscriber field %q", column, fields[i])
+		}
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); ok {
+				im.rowFailed("", fmt.Sprintf("line %d: %s", line, err))
+				continue
+			}
+			return errors.Wrap(err, "unable to read csv")
+		}
+
+		if blankRecord(record) {
+			continue
+		}
+
+		subscriber := SubscriberPayload{}
+		for i, value := range record {
+			if i < len(fields) && fields[i] != "" && value != "" {
+				setSubscriberField(&subscriber, fields[i], value)
+			}
+		}
+
+		if err := im.row(subscriber, line, emit); err != nil {
+			return err
+		}
+	}
+}
+
+func (im *subscriberImport) readJSONL(r io.Reader, emit func(SubscriberPayload) error) error {
+	reader := bufio.NewReader(r)
+
+	for line := 1; ; line++ {
+		b, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return errors.Wrap(err, "unable to read jsonl")
+		}
+
+		if b = bytes.TrimSpace(b); len(b) > 0 {
+			var subscriber SubscriberPayload
+			if decodeErr := json.Unmarshal(b, &subscriber); decodeErr != nil {
+				im.rowFailed("", fmt.Sprintf("line %d: %s", line, decodeErr))
+			} else if rowErr := im.row(subscriber, line, emit); rowErr != nil {
+				return rowErr
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// row validates a decoded subscriber before it is emitted. Blank rows are
+// skipped by the readers, any other row without a subscriberId fails.
+func (im *subscriberImport) row(subscriber SubscriberPayload, line int, emit func(SubscriberPayload) error) error {
+	if subscriber.SubscriberId == "" {
+		im.rowFailed("", fmt.Sprintf("line %d: missing subscriberId", line))
+		return nil
+	}
+
+	im.mu.Lock()
+	im.progress.Read++
+	im.mu.Unlock()
+
+	return emit(subscriber)
+}
+
+func blankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func (im *subscriberImport) rowFailed(subscriberId string, message string) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	im.report.Failed = append(im.report.Failed, SubscriberBulkFailure{SubscriberId: subscriberId, Message: message})
+	im.progress.Failed++
+}
+
+func (im *subscriberImport) chunkDone(chunk []SubscriberPayload, resp SubscriberBulkCreateResponse, err error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	im.progress.Sent += len(chunk)
+	if err != nil {
+		for _, subscriber := range chunk {
+			im.report.Failed = append(im.report.Failed, SubscriberBulkFailure{SubscriberId: subscriber.SubscriberId, Message: err.Error()})
+		}
+		im.progress.Failed += len(chunk)
+	} else {
+		for _, created := range resp.Data.Created {
+			im.report.Created = append(im.report.Created, created.SubscriberId)
+		}
+		for _, updated := range resp.Data.Updated {
+			im.report.Updated = append(im.report.Updated, updated.SubscriberId)
+		}
+		im.report.Failed = append(im.report.Failed, resp.Data.Failed...)
+
+		im.progress.Created += len(resp.Data.Created)
+		im.progress.Updated += len(resp.Data.Updated)
+		im.progress.Failed += len(resp.Data.Failed)
+	}
+
+	if im.opts.Progress != nil {
+		im.opts.Progress(im.progress)
+	}
+}
+
+var subscriberFields = map[string]bool{
+	"subscriberId": true,
+	"firstName":    true,
+	"lastName":     true,
+	"email":        true,
+	"phone":        true,
+	"avatar":       true,
+	"locale":       true,
+}
+
+func isSubscriberField(field string) bool {
+	return subscriberFields[field] || (strings.HasPrefix(field, "data.") && len(field) > len("data."))
+}
+
+// setSubscriberField sets a field accepted by isSubscriberField.
+func setSubscriberField(subscriber *SubscriberPayload, field string, value string) {
+	switch field {
+	case "subscriberId":
+		subscriber.SubscriberId = value
+	case "firstName":
+		subscriber.FirstName = value
+	case "lastName":
+		subscriber.LastName = value
+	case "email":
+		subscriber.Email = value
+	case "phone":
+		subscriber.Phone = value
+	case "avatar":
+		subscriber.Avatar = value
+	case "locale":
+		subscriber.Locale = value
+	default:
+		if subscriber.Data == nil {
+			subscriber.Data = make(map[string]interface{})
+		}
+		subscriber.Data[strings.TrimPrefix(field, "data.")] = value
+	}
+}
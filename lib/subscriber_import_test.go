@@ -0,0 +1,148 @@
+package lib_test
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/saeid-a/go-novu/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bulkCreateServer creates every subscriber it receives, except the ones whose
+// id starts with "existing-" which are updated and "invalid-" which fail.
+func bulkCreateServer(t *testing.T, chunks *[][]lib.SubscriberPayload) *httptest.Server {
+	var mu sync.Mutex
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assureRequestHeaders(t, req, "/v1/subscribers/bulk", http.MethodPost)
+
+		var receivedBody lib.SubscriberBulkPayload
+		if err := json.NewDecoder(req.Body).Decode(&receivedBody); err != nil {
+			log.Printf("error in unmarshalling %+v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		*chunks = append(*chunks, receivedBody.Subscribers)
+		mu.Unlock()
+
+		var resp lib.SubscriberBulkCreateResponse
+		for _, subscriber := range receivedBody.Subscribers {
+			id := struct {
+				SubscriberId string `json:"subscriberId"`
+			}{subscriber.SubscriberId}
+
+			switch {
+			case strings.HasPrefix(subscriber.SubscriberId, "existing-"):
+				resp.Data.Updated = append(resp.Data.Updated, id)
+			case strings.HasPrefix(subscriber.SubscriberId, "invalid-"):
+				resp.Data.Failed = append(resp.Data.Failed, lib.SubscriberBulkFailure{SubscriberId: subscriber.SubscriberId, Message: "invalid email"})
+			default:
+				resp.Data.Created = append(resp.Data.Created, id)
+			}
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		bb, _ := json.Marshal(resp)
+		w.Write(bb)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestSubscriberService_Import_CSV(t *testing.T) {
+	var chunks [][]lib.SubscriberPayload
+	server := bulkCreateServer(t, &chunks)
+
+	input := strings.Join([]string{
+		"id,mail,given_name,plan,ignored",
+		"alice,alice@example.com,Alice,pro,x",
+		"existing-bob,bob@example.com,Bob,,x",
+		",nobody@example.com,Nobody,free,x",
+		"invalid-carol,carol@,Carol,free,x",
+		",,,enterprise,",
+		",,, ,",
+		"",
+	}, "\n")
+
+	var progress []lib.SubscriberImportProgress
+	ctx := context.Background()
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(server.URL)})
+	report, err := c.SubscriberApi.Import(ctx, strings.NewReader(input), lib.SubscriberImportOptions{
+		Format: lib.ImportCSV,
+		Columns: map[string]string{
+			"id":         "subscriberId",
+			"mail":       "email",
+			"given_name": "firstName",
+			"plan":       "data.plan",
+		},
+		ChunkSize: 2,
+		Progress: func(p lib.SubscriberImportProgress) {
+			progress = append(progress, p)
+		},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, chunks, 2)
+	assert.Equal(t, lib.SubscriberPayload{
+		SubscriberId: "alice",
+		Email:        "alice@example.com",
+		FirstName:    "Alice",
+		Data:         map[string]interface{}{"plan": "pro"},
+	}, chunks[0][0])
+	assert.Nil(t, chunks[0][1].Data)
+
+	assert.Equal(t, []string{"alice"}, report.Created)
+	assert.Equal(t, []string{"existing-bob"}, report.Updated)
+	assert.Equal(t, []lib.SubscriberBulkFailure{
+		{Message: "line 4: missing subscriberId"},
+		{Message: "line 6: missing subscriberId"},
+		{SubscriberId: "invalid-carol", Message: "invalid email"},
+	}, report.Failed, "only the blank row is skipped")
+
+	require.Len(t, progress, 2)
+	assert.Equal(t, lib.SubscriberImportProgress{Read: 3, Sent: 3, Created: 1, Updated: 1, Failed: 3}, progress[1])
+}
+
+func TestSubscriberService_Import_JSONL(t *testing.T) {
+	var chunks [][]lib.SubscriberPayload
+	server := bulkCreateServer(t, &chunks)
+
+	var input strings.Builder
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		input.WriteString(`{"subscriberId":"` + id + `","data":{"source":"migration"}}` + "\n")
+	}
+	input.WriteString("{not json}\n")
+
+	ctx := context.Background()
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(server.URL)})
+	report, err := c.SubscriberApi.Import(ctx, strings.NewReader(input.String()), lib.SubscriberImportOptions{
+		Format:      lib.ImportJSONL,
+		ChunkSize:   2,
+		Concurrency: 2,
+	})
+	require.NoError(t, err)
+
+	assert.Len(t, chunks, 3)
+	assert.ElementsMatch(t, []string{"a", "b", "c", "d", "e"}, report.Created)
+	require.Len(t, report.Failed, 1)
+	assert.True(t, strings.HasPrefix(report.Failed[0].Message, "line 6: "))
+}
+
+func TestSubscriberService_BulkCreate_TooManySubscribers(t *testing.T) {
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{})
+
+	_, err := c.SubscriberApi.BulkCreate(context.Background(), lib.SubscriberBulkPayload{
+		Subscribers: make([]lib.SubscriberPayload, lib.MaxBulkSubscribers+1),
+	})
+	require.Error(t, err)
+}
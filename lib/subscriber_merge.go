@@ -0,0 +1,386 @@
+package lib
+
+import (
+	"context"
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// MergeStrategy decides which value is kept when both subscribers of a merge
+// set a field to different values.
+type MergeStrategy int
+
+const (
+	// MergeKeepTarget keeps the values of the target and only fills its empty
+	// fields from the source.
+	MergeKeepTarget MergeStrategy = iota
+	// MergePreferSource overwrites the target with every value set on the
+	// source.
+	MergePreferSource
+)
+
+// SubscriberMergeOptions configures SubscriberService.MergeSubscribers.
+type SubscriberMergeOptions struct {
+	Strategy MergeStrategy
+
+	// Resolve, when set, is called for every conflict instead of applying
+	// Strategy, and returns the value to keep: Target, Source or any other
+	// value of the same type. Returning an error aborts the merge.
+	Resolve func(conflict SubscriberMergeConflict) (interface{}, error)
+
+	// DryRun only returns the plan, without changing anything.
+	DryRun bool
+}
+
+// SubscriberMergeConflict is a field set on both subscribers to different
+// values. Field is a profile field, data.<key> or credentials.<providerId>.
+type SubscriberMergeConflict struct {
+	Field    string      `json:"field"`
+	Target   interface{} `json:"target"`
+	Source   interface{} `json:"source"`
+	Resolved interface{} `json:"resolved"`
+}
+
+// SubscriberMergePlan lists the changes folding the source subscriber into the
+// target one.
+type SubscriberMergePlan struct {
+	TargetId  string                    `json:"targetId"`
+	SourceId  string                    `json:"sourceId"`
+	Conflicts []SubscriberMergeConflict `json:"conflicts"`
+
+	// Changes are the profile and data changes of the target, and Patch the
+	// body sent to SubscriberService.Update.
+	Changes []SubscriberFieldChange `json:"changes"`
+	Patch   map[string]interface{}  `json:"patch"`
+
+	// Credentials are sent to SubscriberService.UpdateCredentials for the
+	// target, with the device tokens of both subscribers.
+	Credentials []SubscriberCredentialPayload `json:"credentials"`
+
+	// Preferences are the workflow preferences of the source carried over to
+	// the target.
+	Preferences []WorkflowPreferenceUpdate `json:"preferences"`
+
+	// Topics are the topics the target is added to, and SourceTopics the ones
+	// the source is removed from before being deleted.
+	Topics       []string `json:"topics"`
+	SourceTopics []string `json:"sourceTopics"`
+}
+
+// MergeSubscribers folds the source subscriber into the target one, e.g. when
+// two accounts are consolidated: profile fields and data are merged, topic
+// memberships are united, preferences and credentials are carried over, then
+// the source is deleted.
+//
+// Data is merged key by key, and device tokens of the same provider are
+// united. Preferences only carry over the opt-outs of the source with
+// MergeKeepTarget, so that merging never subscribes a user back to what they
+// turned off, and every differing preference with MergePreferSource.
+//
+// The changes are applied in the order of the plan and the merge stops at the
+// first failure, keeping the source. As the plan only holds what still
+// differs, calling MergeSubscribers again resumes a failed merge.
+func (s *SubscriberService) MergeSubscribers(ctx context.Context, targetID string, sourceID string, opts *SubscriberMergeOptions) (*SubscriberMergePlan, error) {
+	if opts == nil {
+		opts = &SubscriberMergeOptions{}
+	}
+	if targetID == sourceID {
+		return nil, errors.Errorf("cannot merge subscriber %s into itself", targetID)
+	}
+
+	plan, err := s.planMerge(ctx, targetID, sourceID, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		return plan, nil
+	}
+
+	return plan, s.applyMerge(ctx, plan)
+}
+
+func (s *SubscriberService) planMerge(ctx context.Context, targetID string, sourceID string, opts *SubscriberMergeOptions) (*SubscriberMergePlan, error) {
+	target, err := s.Get(ctx, targetID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get subscriber %s", targetID)
+	}
+	source, err := s.Get(ctx, sourceID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get subscriber %s", sourceID)
+	}
+
+	plan := &SubscriberMergePlan{TargetId: targetID, SourceId: sourceID, Patch: make(map[string]interface{})}
+	merger := &subscriberMerger{opts: opts, plan: plan}
+
+	if err := merger.profile(target.Data, source.Data); err != nil {
+		return nil, err
+	}
+	if err := merger.credentials(target.Data.Channels, source.Data.Channels); err != nil {
+		return nil, err
+	}
+
+	targetPreferences, err := s.GetPreferences(ctx, targetID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get preferences of %s", targetID)
+	}
+	sourcePreferences, err := s.GetPreferences(ctx, sourceID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get preferences of %s", sourceID)
+	}
+	merger.preferences(targetPreferences.Data, sourcePreferences.Data)
+
+	memberships, err := s.topicMemberships(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list topics")
+	}
+	inTarget := make(map[string]bool)
+	for _, key := range memberships[targetID] {
+		inTarget[key] = true
+	}
+	for _, key := range memberships[sourceID] {
+		if !inTarget[key] {
+			plan.Topics = append(plan.Topics, key)
+		}
+		plan.SourceTopics = append(plan.SourceTopics, key)
+	}
+
+	return plan, nil
+}
+
+func (s *SubscriberService) applyMerge(ctx context.Context, plan *SubscriberMergePlan) error {
+	if len(plan.Patch) > 0 {
+		if _, err := s.Update(ctx, plan.TargetId, plan.Patch); err != nil {
+			return errors.Wrapf(err, "unable to update subscriber %s", plan.TargetId)
+		}
+	}
+
+	for _, credentials := range plan.Credentials {
+		if _, err := s.UpdateCredentials(ctx, plan.TargetId, credentials); err != nil {
+			return errors.Wrapf(err, "unable to update %s credentials of %s", credentials.ProviderId, plan.TargetId)
+		}
+	}
+
+	if len(plan.Preferences) > 0 {
+		report, err := s.UpdatePreferencesBulk(ctx, plan.TargetId, plan.Preferences)
+		if err != nil {
+			return err
+		}
+		if len(report.Failed) > 0 {
+			failure := report.Failed[0]
+			return errors.Errorf("unable to update preferences of workflow %s: %s", failure.WorkflowID, failure.Message)
+		}
+	}
+
+	for _, key := range plan.Topics {
+		if err := s.client.TopicsApi.AddSubscribers(ctx, key, []string{plan.TargetId}); err != nil {
+			return errors.Wrapf(err, "unable to add %s to topic %s", plan.TargetId, key)
+		}
+	}
+	for _, key := range plan.SourceTopics {
+		if err := s.client.TopicsApi.RemoveSubscribers(ctx, key, []string{plan.SourceId}); err != nil {
+			return errors.Wrapf(err, "unable to remove %s from topic %s", plan.SourceId, key)
+		}
+	}
+
+	if _, err := s.Delete(ctx, plan.SourceId); err != nil {
+		return errors.Wrapf(err, "unable to delete subscriber %s", plan.SourceId)
+	}
+
+	return nil
+}
+
+type subscriberMerger struct {
+	opts *SubscriberMergeOptions
+	plan *SubscriberMergePlan
+}
+
+// resolve returns the value kept for a field and records the conflict when
+// both values are set and differ.
+func (m *subscriberMerger) resolve(field string, target, source interface{}, empty func(interface{}) bool) (interface{}, error) {
+	switch {
+	case empty(source) || reflect.DeepEqual(target, source):
+		return target, nil
+	case empty(target):
+		return source, nil
+	}
+
+	conflict := SubscriberMergeConflict{Field: field, Target: target, Source: source, Resolved: target}
+	if m.opts.Resolve != nil {
+		resolved, err := m.opts.Resolve(conflict)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to resolve %s", field)
+		}
+		if t := reflect.TypeOf(resolved); resolved == nil || (t != reflect.TypeOf(target) && t != reflect.TypeOf(source)) {
+			return nil, errors.Errorf("resolved %s must be a %T, got %T", field, target, resolved)
+		}
+		conflict.Resolved = resolved
+	} else if m.opts.Strategy == MergePreferSource {
+		conflict.Resolved = source
+	}
+	m.plan.Conflicts = append(m.plan.Conflicts, conflict)
+
+	return conflict.Resolved, nil
+}
+
+func (m *subscriberMerger) profile(target, source Subscriber) error {
+	emptyString := func(v interface{}) bool { return v == "" }
+	isNil := func(v interface{}) bool { return v == nil }
+
+	fields := []struct {
+		name           string
+		target, source string
+	}{
+		{"firstName", target.FirstName, source.FirstName},
+		{"lastName", target.LastName, source.LastName},
+		{"email", target.Email, source.Email},
+		{"phone", target.Phone, source.Phone},
+		{"avatar", target.Avatar, source.Avatar},
+		{"locale", target.Locale, source.Locale},
+	}
+	for _, f := range fields {
+		kept, err := m.resolve(f.name, f.target, f.source, emptyString)
+		if err != nil {
+			return err
+		}
+		if kept != f.target {
+			m.plan.Changes = append(m.plan.Changes, SubscriberFieldChange{Field: f.name, From: f.target, To: kept})
+			m.plan.Patch[f.name] = kept
+		}
+	}
+
+	keys := make([]string, 0, len(source.Data))
+	for key := range source.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	data := make(map[string]interface{}, len(target.Data)+len(source.Data))
+	for key, value := range target.Data {
+		data[key] = value
+	}
+	dataChanged := false
+	for _, key := range keys {
+		value := source.Data[key]
+		from, ok := target.Data[key]
+
+		kept := value
+		if ok {
+			var err error
+			if kept, err = m.resolve("data."+key, from, value, isNil); err != nil {
+				return err
+			}
+			if reflect.DeepEqual(kept, from) {
+				continue
+			}
+		}
+
+		m.plan.Changes = append(m.plan.Changes, SubscriberFieldChange{Field: "data." + key, From: from, To: kept})
+		data[key] = kept
+		dataChanged = true
+	}
+	if dataChanged {
+		m.plan.Patch["data"] = data
+	}
+
+	return nil
+}
+
+// credentials unites the device tokens of every push provider and carries
+// over the other credentials of the source.
+func (m *subscriberMerger) credentials(target, source []SubscriberChannel) error {
+	byProvider := make(map[ProviderIdType]SubscriberChannel, len(target))
+	for _, channel := range target {
+		byProvider[channel.ProviderId] = channel
+	}
+
+	for _, channel := range source {
+		current, ok := byProvider[channel.ProviderId]
+
+		// Device tokens are united, the rest of the credentials is resolved
+		// like any other field.
+		targetOther, sourceOther := current.Credentials, channel.Credentials
+		targetOther.DeviceTokens, sourceOther.DeviceTokens = nil, nil
+		kept, err := m.resolve("credentials."+string(channel.ProviderId), targetOther, sourceOther, func(v interface{}) bool {
+			return reflect.DeepEqual(v, Credentials{})
+		})
+		if err != nil {
+			return err
+		}
+
+		merged := kept.(Credentials)
+		if tokens := dedupeTokens(append(append([]string(nil), current.Credentials.DeviceTokens...), channel.Credentials.DeviceTokens...)); len(tokens) > 0 {
+			merged.DeviceTokens = tokens
+		}
+		if reflect.DeepEqual(kept, targetOther) && tokensEqual(merged.DeviceTokens, current.Credentials.DeviceTokens) {
+			continue
+		}
+
+		integration := channel.IntegrationIdentifier
+		if ok {
+			integration = current.IntegrationIdentifier
+		}
+		m.plan.Credentials = append(m.plan.Credentials, SubscriberCredentialPayload{
+			ProviderId:            channel.ProviderId,
+			IntegrationIdentifier: integration,
+			Credentials:           merged,
+		})
+	}
+
+	return nil
+}
+
+// preferences carries over the workflow preferences of the source. Enabled is
+// the default in Novu, so only the opt-outs of the source are kept with
+// MergeKeepTarget.
+func (m *subscriberMerger) preferences(target, source []SubscriberPreference) {
+	byWorkflow := make(map[string]Preference, len(target))
+	for _, preference := range target {
+		byWorkflow[preference.Template.ID] = preference.Preference
+	}
+
+	carry := func(target, source bool) bool {
+		if m.opts.Strategy == MergePreferSource {
+			return target != source
+		}
+		return target && !source
+	}
+
+	for _, preference := range source {
+		if preference.Template.Critical {
+			continue
+		}
+		current, ok := byWorkflow[preference.Template.ID]
+		if !ok {
+			current = Preference{Enabled: true}
+		}
+
+		if carry(current.Enabled, preference.Preference.Enabled) {
+			enabled := preference.Preference.Enabled
+			m.plan.Preferences = append(m.plan.Preferences, WorkflowPreferenceUpdate{
+				WorkflowID: preference.Template.ID,
+				Options:    UpdateSubscriberPreferencesOptions{Enabled: &enabled},
+			})
+		}
+
+		for _, channel := range PreferenceChannels {
+			sourceEnabled := preference.Preference.Channels.Get(channel)
+			if sourceEnabled == nil {
+				continue
+			}
+			targetEnabled := true
+			if enabled := current.Channels.Get(channel); enabled != nil {
+				targetEnabled = *enabled
+			}
+			if carry(targetEnabled, *sourceEnabled) {
+				m.plan.Preferences = append(m.plan.Preferences, WorkflowPreferenceUpdate{
+					WorkflowID: preference.Template.ID,
+					Options: UpdateSubscriberPreferencesOptions{
+						Channel: &UpdateSubscriberPreferencesChannel{Type: channel, Enabled: *sourceEnabled},
+					},
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package lib_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/saeid-a/go-novu/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mergeSubscribers() map[string]lib.Subscriber {
+	return map[string]lib.Subscriber{
+		"alice": {
+			SubscriberId: "alice",
+			FirstName:    "Alice",
+			Email:        "alice@example.com",
+			Data:         map[string]interface{}{"plan": "pro", "team": "core"},
+			Channels: []lib.SubscriberChannel{
+				{ProviderId: lib.ProviderFCM, Credentials: lib.Credentials{DeviceTokens: []string{"a1"}}},
+				{ProviderId: lib.ProviderSlack, Credentials: lib.Credentials{WebhookUrl: "https://hooks.slack.com/alice"}},
+			},
+		},
+		"bob": {
+			SubscriberId: "bob",
+			FirstName:    "Bob",
+			LastName:     "Smith",
+			Phone:        "+33600000000",
+			Data:         map[string]interface{}{"plan": "free", "country": "fr"},
+			Channels: []lib.SubscriberChannel{
+				{ProviderId: lib.ProviderFCM, Credentials: lib.Credentials{DeviceTokens: []string{"b1", "a1"}}},
+				{ProviderId: lib.ProviderSlack, Credentials: lib.Credentials{WebhookUrl: "https://hooks.slack.com/bob"}},
+				{ProviderId: lib.ProviderDiscord, Credentials: lib.Credentials{WebhookUrl: "https://discord.com/bob"}},
+			},
+		},
+	}
+}
+
+const mergePreferences = `{
+	"alice": {"data": [
+		{"template": {"_id": "wf1", "name": "Digest"}, "preference": {"enabled": true, "channels": {"email": true, "sms": false}}}
+	]},
+	"bob": {"data": [
+		{"template": {"_id": "wf1", "name": "Digest"}, "preference": {"enabled": true, "channels": {"email": false, "sms": true}}},
+		{"template": {"_id": "wf2", "name": "News"}, "preference": {"enabled": false, "channels": {"email": true}}},
+		{"template": {"_id": "wf3", "name": "Security", "critical": true}, "preference": {"enabled": false, "channels": {"email": false}}}
+	]}
+}`
+
+var mergeTopics = []lib.GetTopicResponse{
+	{Key: "news", Subscribers: []string{"alice", "bob"}},
+	{Key: "sport", Subscribers: []string{"bob"}},
+}
+
+// mergeServer serves alice, bob and the given topics, and records every
+// write with its body.
+func mergeServer(t *testing.T, topics []lib.GetTopicResponse, writes *[]string, bodies map[string]json.RawMessage) *httptest.Server {
+	var mu sync.Mutex
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		path := strings.TrimPrefix(req.URL.Path, "/v1/")
+		if req.Method != http.MethodGet {
+			var body json.RawMessage
+			_ = json.NewDecoder(req.Body).Decode(&body)
+			call := req.Method + " " + path
+			*writes = append(*writes, call)
+			bodies[call] = body
+			w.Write([]byte(`{"data": {}}`))
+			return
+		}
+
+		switch {
+		case path == "topics":
+			bb, _ := json.Marshal(listTopicsPage(req, topics))
+			w.Write(bb)
+		case strings.HasSuffix(path, "/preferences"):
+			var preferences map[string]json.RawMessage
+			_ = json.Unmarshal([]byte(mergePreferences), &preferences)
+			w.Write(preferences[strings.TrimSuffix(strings.TrimPrefix(path, "subscribers/"), "/preferences")])
+		default:
+			subscriber, ok := mergeSubscribers()[strings.TrimPrefix(path, "subscribers/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			bb, _ := json.Marshal(lib.SubscriberResponse{Data: subscriber})
+			w.Write(bb)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestSubscriberService_MergeSubscribers_DryRun(t *testing.T) {
+	var writes []string
+	bodies := make(map[string]json.RawMessage)
+	httpServer := mergeServer(t, mergeTopics, &writes, bodies)
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+
+	plan, err := c.SubscriberApi.MergeSubscribers(context.Background(), "alice", "bob", &lib.SubscriberMergeOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Empty(t, writes)
+
+	assert.Equal(t, []lib.SubscriberMergeConflict{
+		{Field: "firstName", Target: "Alice", Source: "Bob", Resolved: "Alice"},
+		{Field: "data.plan", Target: "pro", Source: "free", Resolved: "pro"},
+		{
+			Field:    "credentials.slack",
+			Target:   lib.Credentials{WebhookUrl: "https://hooks.slack.com/alice"},
+			Source:   lib.Credentials{WebhookUrl: "https://hooks.slack.com/bob"},
+			Resolved: lib.Credentials{WebhookUrl: "https://hooks.slack.com/alice"},
+		},
+	}, plan.Conflicts)
+
+	assert.Equal(t, []lib.SubscriberFieldChange{
+		{Field: "lastName", From: "", To: "Smith"},
+		{Field: "phone", From: "", To: "+33600000000"},
+		{Field: "data.country", From: nil, To: "fr"},
+	}, plan.Changes)
+	assert.Equal(t, map[string]interface{}{
+		"lastName": "Smith",
+		"phone":    "+33600000000",
+		"data":     map[string]interface{}{"plan": "pro", "team": "core", "country": "fr"},
+	}, plan.Patch)
+
+	assert.Equal(t, []lib.SubscriberCredentialPayload{
+		{ProviderId: lib.ProviderFCM, Credentials: lib.Credentials{DeviceTokens: []string{"a1", "b1"}}},
+		{ProviderId: lib.ProviderDiscord, Credentials: lib.Credentials{WebhookUrl: "https://discord.com/bob"}},
+	}, plan.Credentials)
+
+	disabled := false
+	assert.Equal(t, []lib.WorkflowPreferenceUpdate{
+		{WorkflowID: "wf1", Options: lib.UpdateSubscriberPreferencesOptions{Channel: &lib.UpdateSubscriberPreferencesChannel{Type: lib.EMAIL, Enabled: false}}},
+		{WorkflowID: "wf2", Options: lib.UpdateSubscriberPreferencesOptions{Enabled: &disabled}},
+	}, plan.Preferences, "only the opt-outs of bob are carried over")
+
+	assert.Equal(t, []string{"sport"}, plan.Topics)
+	assert.Equal(t, []string{"news", "sport"}, plan.SourceTopics)
+}
+
+func TestSubscriberService_MergeSubscribers(t *testing.T) {
+	var writes []string
+	bodies := make(map[string]json.RawMessage)
+	httpServer := mergeServer(t, mergeTopics, &writes, bodies)
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+
+	var conflicts []string
+	plan, err := c.SubscriberApi.MergeSubscribers(context.Background(), "alice", "bob", &lib.SubscriberMergeOptions{
+		Strategy: lib.MergePreferSource,
+		Resolve: func(conflict lib.SubscriberMergeConflict) (interface{}, error) {
+			conflicts = append(conflicts, conflict.Field)
+			if conflict.Field == "firstName" {
+				return "Alice", nil
+			}
+			return conflict.Source, nil
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"firstName", "data.plan", "credentials.slack"}, conflicts)
+	assert.Len(t, plan.Preferences, 3, "every differing preference of bob is carried over")
+
+	// Preferences are updated concurrently, in no particular order.
+	require.Len(t, writes, 11)
+	assert.Equal(t, []string{
+		"PUT subscribers/alice",
+		"PUT subscribers/alice/credentials",
+		"PUT subscribers/alice/credentials",
+		"PUT subscribers/alice/credentials",
+	}, writes[:4])
+	assert.ElementsMatch(t, []string{
+		"PATCH subscribers/alice/preferences/wf1",
+		"PATCH subscribers/alice/preferences/wf1",
+		"PATCH subscribers/alice/preferences/wf2",
+	}, writes[4:7])
+	assert.Equal(t, []string{
+		"POST topics/sport/subscribers",
+		"POST topics/news/subscribers/removal",
+		"POST topics/sport/subscribers/removal",
+		"DELETE subscribers/bob",
+	}, writes[7:])
+
+	assert.JSONEq(t, `{"lastName":"Smith","phone":"+33600000000","data":{"plan":"free","team":"core","country":"fr"}}`,
+		string(bodies["PUT subscribers/alice"]))
+	assert.JSONEq(t, `{"subscribers":["alice"]}`, string(bodies["POST topics/sport/subscribers"]))
+	assert.JSONEq(t, `{"subscribers":["bob"]}`, string(bodies["POST topics/sport/subscribers/removal"]))
+}
+
+func TestSubscriberService_MergeSubscribers_Errors(t *testing.T) {
+	var writes []string
+	bodies := make(map[string]json.RawMessage)
+	httpServer := mergeServer(t, mergeTopics, &writes, bodies)
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+	ctx := context.Background()
+
+	_, err := c.SubscriberApi.MergeSubscribers(ctx, "alice", "alice", nil)
+	assert.Error(t, err)
+
+	_, err = c.SubscriberApi.MergeSubscribers(ctx, "alice", "bob", &lib.SubscriberMergeOptions{
+		Resolve: func(lib.SubscriberMergeConflict) (interface{}, error) { return 42, nil },
+	})
+	assert.Error(t, err, "a resolved value must have the type of the field")
+
+	_, err = c.SubscriberApi.MergeSubscribers(ctx, "alice", "carol", nil)
+	assert.Error(t, err)
+	assert.Empty(t, writes)
+}
+
+func TestSubscriberService_MergeSubscribers_TopicPages(t *testing.T) {
+	topics := append([]lib.GetTopicResponse(nil), mergeTopics...)
+	for i := 0; i < lib.DefaultTopicsPageSize; i++ {
+		topics = append(topics, lib.GetTopicResponse{Key: fmt.Sprintf("topic-%03d", i)})
+	}
+	topics = append(topics,
+		lib.GetTopicResponse{Key: "late-shared", Subscribers: []string{"bob", "alice"}},
+		lib.GetTopicResponse{Key: "late", Subscribers: []string{"bob"}},
+	)
+
+	var writes []string
+	bodies := make(map[string]json.RawMessage)
+	httpServer := mergeServer(t, topics, &writes, bodies)
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+
+	plan, err := c.SubscriberApi.MergeSubscribers(context.Background(), "alice", "bob", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"late", "sport"}, plan.Topics)
+	assert.Equal(t, []string{"late", "late-shared", "news", "sport"}, plan.SourceTopics)
+	assert.Subset(t, writes, []string{
+		"POST topics/late/subscribers",
+		"POST topics/late/subscribers/removal",
+		"POST topics/late-shared/subscribers/removal",
+	})
+	assert.Equal(t, "DELETE subscribers/bob", writes[len(writes)-1])
+}
@@ -0,0 +1,269 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// SubscriberSessionOptions identifies the subscriber a SubscriberSessionClient
+// acts for.
+type SubscriberSessionOptions struct {
+	ApplicationIdentifier string `json:"applicationIdentifier"`
+	SubscriberId          string `json:"subscriberId"`
+	// SubscriberHash is required when HMAC encryption is enabled on the
+	// in-app integration, see APIClient.SubscriberHash.
+	SubscriberHash string `json:"hmacHash,omitempty"`
+	// The profile fields below update the subscriber on initialize.
+	FirstName string `json:"firstName,omitempty"`
+	LastName  string `json:"lastName,omitempty"`
+	Email     string `json:"email,omitempty"`
+	Phone     string `json:"phone,omitempty"`
+}
+
+type SubscriberSession struct {
+	Token   string     `json:"token"`
+	Profile Subscriber `json:"profile"`
+}
+
+type SubscriberSessionResponse struct {
+	Data SubscriberSession `json:"data"`
+}
+
+// SubscriberSessionClient calls the widget endpoints on behalf of a single
+// subscriber, the way the in-app widget does. The session is initialized on
+// the first call and again whenever the token is rejected.
+type SubscriberSessionClient struct {
+	client *APIClient
+	opts   SubscriberSessionOptions
+
+	mu      sync.Mutex
+	session *SubscriberSession
+}
+
+func NewSubscriberSessionClient(client *APIClient, opts SubscriberSessionOptions) *SubscriberSessionClient {
+	return &SubscriberSessionClient{client: client, opts: opts}
+}
+
+// Initialize starts a new session, replacing the current one, and returns the
+// subscriber profile.
+func (c *SubscriberSessionClient) Initialize(ctx context.Context) (*Subscriber, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	session, err := c.initialize(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session.Profile, nil
+}
+
+func (c *SubscriberSessionClient) initialize(ctx context.Context) (*SubscriberSession, error) {
+	var resp SubscriberSessionResponse
+	URL := c.client.config.BackendURL.JoinPath("widgets", "session", "initialize")
+
+	jsonBody, err := json.Marshal(c.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, URL.String(), bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.client.doRequest(req, &resp)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to initialize subscriber session")
+	}
+	if resp.Data.Token == "" {
+		return nil, errors.New("unable to initialize subscriber session: no token returned")
+	}
+
+	c.session = &resp.Data
+	return c.session, nil
+}
+
+// token returns the session token, initializing a session when there is none
+// or when the current token is the expired one.
+func (c *SubscriberSessionClient) token(ctx context.Context, expired string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.session != nil && c.session.Token != expired {
+		return c.session.Token, nil
+	}
+
+	session, err := c.initialize(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return session.Token, nil
+}
+
+// send calls a widget endpoint and retries once with a new session when the
+// token is rejected.
+func (c *SubscriberSessionClient) send(ctx context.Context, method string, URL *url.URL, body interface{}, resp interface{}) error {
+	var jsonBody []byte
+	if body != nil {
+		var err error
+		if jsonBody, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	var expired string
+	for attempt := 0; ; attempt++ {
+		token, err := c.token(ctx, expired)
+		if err != nil {
+			return err
+		}
+
+		var reqBody io.Reader = http.NoBody
+		if jsonBody != nil {
+			reqBody = bytes.NewReader(jsonBody)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, URL.String(), reqBody)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		res, err := c.client.doRequest(req, resp)
+		if err != nil && attempt == 0 && res != nil && res.StatusCode == http.StatusUnauthorized {
+			expired = token
+			continue
+		}
+
+		return err
+	}
+}
+
+// GetNotificationFeed returns a page of the subscriber in-app feed.
+func (c *SubscriberSessionClient) GetNotificationFeed(ctx context.Context, opts *SubscriberNotificationFeedOptions) (*SubscriberNotificationFeedResponse, error) {
+	var resp SubscriberNotificationFeedResponse
+	URL := c.client.config.BackendURL.JoinPath("widgets", "notifications", "feed")
+
+	query, err := EncodeQuery(opts)
+	if err != nil {
+		return nil, err
+	}
+	URL.RawQuery = query.Encode()
+
+	if err := c.send(ctx, http.MethodGet, URL, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// GetUnseenCount returns the number of messages matching opts, the unseen
+// ones when opts is nil.
+func (c *SubscriberSessionClient) GetUnseenCount(ctx context.Context, opts *SubscriberUnseenCountOptions) (*SubscriberUnseenCountResponse, error) {
+	var resp SubscriberUnseenCountResponse
+	URL := c.client.config.BackendURL.JoinPath("widgets", "notifications", "unseen")
+
+	query, err := EncodeQuery(opts)
+	if err != nil {
+		return nil, err
+	}
+	URL.RawQuery = query.Encode()
+
+	if err := c.send(ctx, http.MethodGet, URL, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// MarkMessages sets the seen and read flags of several messages at once and
+// returns the updated messages.
+func (c *SubscriberSessionClient) MarkMessages(ctx context.Context, opts SubscriberMarkMessagesOptions) ([]NotificationFeedData, error) {
+	var resp MarkMessagesResponse
+	URL := c.client.config.BackendURL.JoinPath("widgets", "messages", "markAs")
+
+	if err := c.send(ctx, http.MethodPost, URL, opts, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Data, nil
+}
+
+// MarkAllAsRead marks every message of the subscriber, or of a single feed
+// when feedIdentifier is set, as read and returns the number of messages
+// updated.
+func (c *SubscriberSessionClient) MarkAllAsRead(ctx context.Context, feedIdentifier string) (int, error) {
+	return c.markAll(ctx, "read", feedIdentifier)
+}
+
+// MarkAllAsSeen is MarkAllAsRead for the seen flag.
+func (c *SubscriberSessionClient) MarkAllAsSeen(ctx context.Context, feedIdentifier string) (int, error) {
+	return c.markAll(ctx, "seen", feedIdentifier)
+}
+
+func (c *SubscriberSessionClient) markAll(ctx context.Context, mark string, feedIdentifier string) (int, error) {
+	var resp MarkAllMessagesResponse
+	URL := c.client.config.BackendURL.JoinPath("widgets", "messages", mark)
+
+	body := map[string]string{}
+	if feedIdentifier != "" {
+		body["feedIdentifier"] = feedIdentifier
+	}
+
+	if err := c.send(ctx, http.MethodPost, URL, body, &resp); err != nil {
+		return 0, err
+	}
+
+	return resp.Data, nil
+}
+
+// GetPreferences returns the workflow preferences of the subscriber.
+func (c *SubscriberSessionClient) GetPreferences(ctx context.Context) (*SubscriberPreferencesResponse, error) {
+	var resp SubscriberPreferencesResponse
+	URL := c.client.config.BackendURL.JoinPath("widgets", "preferences")
+
+	if err := c.send(ctx, http.MethodGet, URL, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// UpdatePreferences updates the preferences of the subscriber for a workflow.
+func (c *SubscriberSessionClient) UpdatePreferences(ctx context.Context, templateId string, opts *UpdateSubscriberPreferencesOptions) (*UpdateSubscriberPreferencesResponse, error) {
+	var resp UpdateSubscriberPreferencesResponse
+	URL := c.client.config.BackendURL.JoinPath("widgets", "preferences", templateId)
+
+	var body interface{}
+	if opts != nil {
+		body = opts
+	}
+
+	if err := c.send(ctx, http.MethodPatch, URL, body, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// UpdateGlobalPreferences updates the channel defaults of the subscriber.
+func (c *SubscriberSessionClient) UpdateGlobalPreferences(ctx context.Context, opts UpdateGlobalPreferencesOptions) (*Preference, error) {
+	var resp UpdateGlobalPreferencesResponse
+	URL := c.client.config.BackendURL.JoinPath("widgets", "preferences")
+
+	if err := c.send(ctx, http.MethodPatch, URL, opts, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp.Data.Preference, nil
+}
@@ -0,0 +1,168 @@
+package lib_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/saeid-a/go-novu/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sessionState records the calls made to sessionServer. token is the only
+// token accepted, setting it to anything else simulates an expired session.
+type sessionState struct {
+	inits    []lib.SubscriberSessionOptions
+	token    string
+	requests []*http.Request
+	bodies   []string
+}
+
+// sessionServer serves the widget endpoints. Every initialize issues a new
+// token.
+func sessionServer(t *testing.T, state *sessionState) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/v1/widgets/session/initialize" {
+			var opts lib.SubscriberSessionOptions
+			_ = json.NewDecoder(req.Body).Decode(&opts)
+			state.inits = append(state.inits, opts)
+			state.token = fmt.Sprintf("token-%d", len(state.inits))
+
+			bb, _ := json.Marshal(lib.SubscriberSessionResponse{Data: lib.SubscriberSession{
+				Token:   state.token,
+				Profile: lib.Subscriber{SubscriberId: opts.SubscriberId},
+			}})
+			w.Write(bb)
+			return
+		}
+
+		if req.Header.Get("Authorization") != "Bearer "+state.token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var body json.RawMessage
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		state.requests = append(state.requests, req)
+		state.bodies = append(state.bodies, string(body))
+
+		switch req.URL.Path {
+		case "/v1/widgets/notifications/feed":
+			http.ServeFile(w, req, "../testdata/subscriber_notification_feed_response.json")
+		case "/v1/widgets/notifications/unseen":
+			w.Write([]byte(`{"data":{"count":3}}`))
+		case "/v1/widgets/messages/markAs":
+			w.Write([]byte(`{"data":[{"_id":"m1","seen":true,"read":true}]}`))
+		case "/v1/widgets/messages/read":
+			w.Write([]byte(`{"data":5}`))
+		case "/v1/widgets/preferences":
+			http.ServeFile(w, req, "../testdata/subscriber_preferences_response.json")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func sessionOptions() lib.SubscriberSessionOptions {
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{})
+	return lib.SubscriberSessionOptions{
+		ApplicationIdentifier: "app-id",
+		SubscriberId:          subscriberID,
+		SubscriberHash:        c.SubscriberHash(subscriberID),
+	}
+}
+
+func TestSubscriberSessionClient_Initialize(t *testing.T) {
+	state := &sessionState{}
+	httpServer := sessionServer(t, state)
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+	session := lib.NewSubscriberSessionClient(c, sessionOptions())
+
+	profile, err := session.Initialize(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, subscriberID, profile.SubscriberId)
+
+	require.Len(t, state.inits, 1)
+	assert.Equal(t, sessionOptions(), state.inits[0])
+}
+
+func TestSubscriberSessionClient_GetNotificationFeed(t *testing.T) {
+	state := &sessionState{}
+	httpServer := sessionServer(t, state)
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+	session := lib.NewSubscriberSessionClient(c, sessionOptions())
+
+	var expected *lib.SubscriberNotificationFeedResponse
+	fileToStruct("../testdata/subscriber_notification_feed_response.json", &expected)
+
+	seen := false
+	resp, err := session.GetNotificationFeed(context.Background(), &lib.SubscriberNotificationFeedOptions{Page: 1, Seen: &seen})
+	require.NoError(t, err)
+	assert.Equal(t, expected, resp)
+
+	require.Len(t, state.inits, 1, "the session is initialized on first use")
+	assert.Equal(t, "page=1&seen=false", state.requests[0].URL.RawQuery)
+}
+
+func TestSubscriberSessionClient_ExpiredSession(t *testing.T) {
+	state := &sessionState{}
+	httpServer := sessionServer(t, state)
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+	session := lib.NewSubscriberSessionClient(c, sessionOptions())
+	ctx := context.Background()
+
+	_, err := session.GetUnseenCount(ctx, nil)
+	require.NoError(t, err)
+
+	state.token = "revoked"
+	resp, err := session.GetUnseenCount(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, resp.Data.Count)
+
+	assert.Len(t, state.inits, 2)
+	assert.Len(t, state.requests, 2)
+}
+
+func TestSubscriberSessionClient_MarkMessages(t *testing.T) {
+	state := &sessionState{}
+	httpServer := sessionServer(t, state)
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+	session := lib.NewSubscriberSessionClient(c, sessionOptions())
+	ctx := context.Background()
+
+	read := true
+	messages, err := session.MarkMessages(ctx, lib.SubscriberMarkMessagesOptions{
+		MessageIDs: []string{"m1"},
+		Mark:       lib.MessageMark{Read: &read},
+	})
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.True(t, messages[0].Read)
+	assert.JSONEq(t, `{"messageId":["m1"],"mark":{"read":true}}`, state.bodies[0])
+
+	count, err := session.MarkAllAsRead(ctx, "updates")
+	require.NoError(t, err)
+	assert.Equal(t, 5, count)
+	assert.JSONEq(t, `{"feedIdentifier":"updates"}`, state.bodies[1])
+}
+
+func TestSubscriberSessionClient_GetPreferences(t *testing.T) {
+	state := &sessionState{}
+	httpServer := sessionServer(t, state)
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+	session := lib.NewSubscriberSessionClient(c, sessionOptions())
+
+	var expected *lib.SubscriberPreferencesResponse
+	fileToStruct("../testdata/subscriber_preferences_response.json", &expected)
+
+	resp, err := session.GetPreferences(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, expected, resp)
+}
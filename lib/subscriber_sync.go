@@ -0,0 +1,244 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// SubscriberSyncOptions configures a SubscriberSync.
+type SubscriberSyncOptions struct {
+	// Prune plans the deletion of the subscribers missing from the desired set.
+	Prune bool
+
+	// MaxDeletes is the largest number of deletions Apply accepts. A negative
+	// value disables the limit.
+	MaxDeletes int
+
+	// DryRun makes Apply check the plan without changing anything.
+	DryRun bool
+
+	// PageSize is used to list the current subscribers. Defaults to
+	// DefaultSubscribersPageSize.
+	PageSize int
+}
+
+type SubscriberFieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+type SubscriberSyncUpdate struct {
+	SubscriberId string                  `json:"subscriberId"`
+	Changes      []SubscriberFieldChange `json:"changes"`
+	// Patch is the body sent to SubscriberService.Update.
+	Patch map[string]interface{} `json:"patch"`
+}
+
+type SubscriberSyncPlan struct {
+	Creates []SubscriberPayload    `json:"creates"`
+	Updates []SubscriberSyncUpdate `json:"updates"`
+	Deletes []string               `json:"deletes"`
+}
+
+func (p *SubscriberSyncPlan) Empty() bool {
+	return len(p.Creates) == 0 && len(p.Updates) == 0 && len(p.Deletes) == 0
+}
+
+// SubscriberSyncResult lists the subscribers Apply changed. Updated also holds
+// the subscribers planned for creation that BulkCreate found and updated,
+// e.g. because they were created since the plan.
+type SubscriberSyncResult struct {
+	DryRun  bool
+	Created []string
+	Updated []string
+	Deleted []string
+	Failed  []SubscriberBulkFailure
+}
+
+// SubscriberSync reconciles the subscribers of Novu with a desired set coming
+// from the system of record.
+//
+// Only the fields set on a desired subscriber are compared: an empty field
+// is left as it is in Novu, and data keys missing from the desired
+// subscriber are kept.
+type SubscriberSync struct {
+	client *APIClient
+	opts   SubscriberSyncOptions
+}
+
+func NewSubscriberSync(client *APIClient, opts SubscriberSyncOptions) *SubscriberSync {
+	if opts.PageSize <= 0 {
+		opts.PageSize = DefaultSubscribersPageSize
+	}
+
+	return &SubscriberSync{client: client, opts: opts}
+}
+
+// Plan diffs the desired subscribers, produced by calling yield for each of
+// them, against the subscribers currently in Novu.
+func (s *SubscriberSync) Plan(ctx context.Context, desired func(yield func(SubscriberPayload) error) error) (*SubscriberSyncPlan, error) {
+	current := make(map[string]Subscriber)
+	err := s.client.SubscriberApi.ListAll(ctx, s.opts.PageSize, func(subscriber Subscriber) error {
+		current[subscriber.SubscriberId] = subscriber
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &SubscriberSyncPlan{}
+	seen := make(map[string]bool)
+
+	err = desired(func(subscriber SubscriberPayload) error {
+		if subscriber.SubscriberId == "" {
+			return errors.New("desired subscriber without subscriberId")
+		}
+		if seen[subscriber.SubscriberId] {
+			return errors.Errorf("desired subscriber %s given twice", subscriber.SubscriberId)
+		}
+		seen[subscriber.SubscriberId] = true
+
+		existing, ok := current[subscriber.SubscriberId]
+		if !ok {
+			plan.Creates = append(plan.Creates, subscriber)
+			return nil
+		}
+
+		if update, changed := diffSubscriber(existing, subscriber); changed {
+			plan.Updates = append(plan.Updates, update)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.opts.Prune {
+		for subscriberId := range current {
+			if !seen[subscriberId] {
+				plan.Deletes = append(plan.Deletes, subscriberId)
+			}
+		}
+		sort.Strings(plan.Deletes)
+	}
+
+	return plan, nil
+}
+
+// Apply executes the plan: creations through BulkCreate, then updates, then
+// deletions. Individual failures are reported in the result; an error is only
+// returned when the plan breaks the safety limits or the context is done.
+func (s *SubscriberSync) Apply(ctx context.Context, plan *SubscriberSyncPlan) (*SubscriberSyncResult, error) {
+	if s.opts.MaxDeletes >= 0 && len(plan.Deletes) > s.opts.MaxDeletes {
+		return nil, errors.Errorf("plan deletes %d subscribers, more than the allowed %d", len(plan.Deletes), s.opts.MaxDeletes)
+	}
+
+	result := &SubscriberSyncResult{DryRun: s.opts.DryRun}
+	if s.opts.DryRun {
+		return result, nil
+	}
+
+	subscribers := s.client.SubscriberApi
+	for start := 0; start < len(plan.Creates); start += MaxBulkSubscribers {
+		end := start + MaxBulkSubscribers
+		if end > len(plan.Creates) {
+			end = len(plan.Creates)
+		}
+
+		resp, err := subscribers.BulkCreate(ctx, SubscriberBulkPayload{Subscribers: plan.Creates[start:end]})
+		if err != nil {
+			for _, subscriber := range plan.Creates[start:end] {
+				result.Failed = append(result.Failed, SubscriberBulkFailure{SubscriberId: subscriber.SubscriberId, Message: err.Error()})
+			}
+			continue
+		}
+		for _, created := range resp.Data.Created {
+			result.Created = append(result.Created, created.SubscriberId)
+		}
+		for _, updated := range resp.Data.Updated {
+			result.Updated = append(result.Updated, updated.SubscriberId)
+		}
+		result.Failed = append(result.Failed, resp.Data.Failed...)
+	}
+
+	for _, update := range plan.Updates {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		if _, err := subscribers.Update(ctx, update.SubscriberId, update.Patch); err != nil {
+			result.Failed = append(result.Failed, SubscriberBulkFailure{SubscriberId: update.SubscriberId, Message: err.Error()})
+			continue
+		}
+		result.Updated = append(result.Updated, update.SubscriberId)
+	}
+
+	for _, subscriberId := range plan.Deletes {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		if _, err := subscribers.Delete(ctx, subscriberId); err != nil {
+			result.Failed = append(result.Failed, SubscriberBulkFailure{SubscriberId: subscriberId, Message: err.Error()})
+			continue
+		}
+		result.Deleted = append(result.Deleted, subscriberId)
+	}
+
+	return result, ctx.Err()
+}
+
+func diffSubscriber(current Subscriber, desired SubscriberPayload) (SubscriberSyncUpdate, bool) {
+	update := SubscriberSyncUpdate{SubscriberId: desired.SubscriberId, Patch: make(map[string]interface{})}
+
+	fields := []struct {
+		name          string
+		current, want string
+	}{
+		{"firstName", current.FirstName, desired.FirstName},
+		{"lastName", current.LastName, desired.LastName},
+		{"email", current.Email, desired.Email},
+		{"phone", current.Phone, desired.Phone},
+		{"avatar", current.Avatar, desired.Avatar},
+		{"locale", current.Locale, desired.Locale},
+	}
+	for _, f := range fields {
+		if f.want != "" && f.want != f.current {
+			update.Changes = append(update.Changes, SubscriberFieldChange{Field: f.name, From: f.current, To: f.want})
+			update.Patch[f.name] = f.want
+		}
+	}
+
+	if len(desired.Data) > 0 {
+		var want map[string]interface{}
+		b, _ := json.Marshal(desired.Data)
+		_ = json.Unmarshal(b, &want)
+
+		keys := make([]string, 0, len(want))
+		for key := range want {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		data := make(map[string]interface{}, len(current.Data)+len(want))
+		for key, value := range current.Data {
+			data[key] = value
+		}
+		dataChanged := false
+		for _, key := range keys {
+			if from, ok := current.Data[key]; !ok || !reflect.DeepEqual(from, want[key]) {
+				update.Changes = append(update.Changes, SubscriberFieldChange{Field: "data." + key, From: current.Data[key], To: want[key]})
+				data[key] = want[key]
+				dataChanged = true
+			}
+		}
+		if dataChanged {
+			update.Patch["data"] = data
+		}
+	}
+
+	return update, len(update.Changes) > 0
+}
@@ -0,0 +1,207 @@
+package lib_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/saeid-a/go-novu/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncState holds the subscribers served by syncServer and records the calls
+// made against them. The subscribers listed in existing are answered as
+// updated by the bulk create endpoint.
+type syncState struct {
+	subscribers []lib.Subscriber
+	existing    map[string]bool
+	bulk        int
+	updates     map[string]map[string]interface{}
+	deletes     []string
+}
+
+func newSyncState() *syncState {
+	return &syncState{
+		updates: make(map[string]map[string]interface{}),
+		subscribers: []lib.Subscriber{
+			{SubscriberId: "alice", FirstName: "Alice", Email: "alice@example.com", Data: map[string]interface{}{"plan": "free", "seats": float64(1)}},
+			{SubscriberId: "bob", FirstName: "Bob"},
+			{SubscriberId: "carol", FirstName: "Carol"},
+		},
+	}
+}
+
+func syncServer(t *testing.T, state *syncState) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id := strings.TrimPrefix(req.URL.Path, "/v1/subscribers/")
+		switch {
+		case req.Method == http.MethodGet && req.URL.Path == "/v1/subscribers":
+			bb, _ := json.Marshal(lib.ListSubscribersResponse{Data: state.subscribers})
+			w.Write(bb)
+		case req.Method == http.MethodPost && id == "bulk":
+			var body lib.SubscriberBulkPayload
+			_ = json.NewDecoder(req.Body).Decode(&body)
+			state.bulk++
+
+			var resp lib.SubscriberBulkCreateResponse
+			for _, subscriber := range body.Subscribers {
+				entry := struct {
+					SubscriberId string `json:"subscriberId"`
+				}{subscriber.SubscriberId}
+				if state.existing[subscriber.SubscriberId] {
+					resp.Data.Updated = append(resp.Data.Updated, entry)
+				} else {
+					resp.Data.Created = append(resp.Data.Created, entry)
+				}
+			}
+			bb, _ := json.Marshal(resp)
+			w.Write(bb)
+		case req.Method == http.MethodPut:
+			var patch map[string]interface{}
+			_ = json.NewDecoder(req.Body).Decode(&patch)
+			state.updates[id] = patch
+			w.Write([]byte(`{"data":{}}`))
+		case req.Method == http.MethodDelete:
+			state.deletes = append(state.deletes, id)
+			w.Write([]byte(`{"data":{"acknowledged":true,"status":"deleted"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func desiredSubscribers(subscribers ...lib.SubscriberPayload) func(yield func(lib.SubscriberPayload) error) error {
+	return func(yield func(lib.SubscriberPayload) error) error {
+		for _, subscriber := range subscribers {
+			if err := yield(subscriber); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestSubscriberSync_Plan(t *testing.T) {
+	server := syncServer(t, newSyncState())
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(server.URL)})
+	syncer := lib.NewSubscriberSync(c, lib.SubscriberSyncOptions{Prune: true})
+
+	plan, err := syncer.Plan(context.Background(), desiredSubscribers(
+		lib.SubscriberPayload{SubscriberId: "alice", Email: "alice@example.org", Data: map[string]interface{}{"plan": "pro", "seats": 1}},
+		lib.SubscriberPayload{SubscriberId: "bob", FirstName: "Bob"},
+		lib.SubscriberPayload{SubscriberId: "dave", FirstName: "Dave"},
+	))
+	require.NoError(t, err)
+
+	require.Len(t, plan.Creates, 1)
+	assert.Equal(t, "dave", plan.Creates[0].SubscriberId)
+
+	require.Len(t, plan.Updates, 1)
+	update := plan.Updates[0]
+	assert.Equal(t, "alice", update.SubscriberId)
+	assert.Equal(t, []lib.SubscriberFieldChange{
+		{Field: "email", From: "alice@example.com", To: "alice@example.org"},
+		{Field: "data.plan", From: "free", To: "pro"},
+	}, update.Changes)
+	assert.Equal(t, map[string]interface{}{"plan": "pro", "seats": float64(1)}, update.Patch["data"])
+
+	assert.Equal(t, []string{"carol"}, plan.Deletes)
+}
+
+func TestSubscriberSync_Plan_DuplicateDesired(t *testing.T) {
+	server := syncServer(t, newSyncState())
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(server.URL)})
+
+	_, err := lib.NewSubscriberSync(c, lib.SubscriberSyncOptions{}).Plan(context.Background(), desiredSubscribers(
+		lib.SubscriberPayload{SubscriberId: "dave"},
+		lib.SubscriberPayload{SubscriberId: "dave"},
+	))
+	assert.Error(t, err)
+}
+
+func TestSubscriberSync_Apply(t *testing.T) {
+	state := newSyncState()
+	server := syncServer(t, state)
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(server.URL)})
+	syncer := lib.NewSubscriberSync(c, lib.SubscriberSyncOptions{Prune: true, MaxDeletes: 1})
+
+	plan, err := syncer.Plan(context.Background(), desiredSubscribers(
+		lib.SubscriberPayload{SubscriberId: "alice", FirstName: "Alicia"},
+		lib.SubscriberPayload{SubscriberId: "bob"},
+		lib.SubscriberPayload{SubscriberId: "dave"},
+	))
+	require.NoError(t, err)
+
+	result, err := syncer.Apply(context.Background(), plan)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"dave"}, result.Created)
+	assert.Equal(t, []string{"alice"}, result.Updated)
+	assert.Equal(t, []string{"carol"}, result.Deleted)
+	assert.Empty(t, result.Failed)
+
+	assert.Equal(t, 1, state.bulk)
+	assert.Equal(t, map[string]interface{}{"firstName": "Alicia"}, state.updates["alice"])
+	assert.Equal(t, []string{"carol"}, state.deletes)
+}
+
+func TestSubscriberSync_Apply_DryRun(t *testing.T) {
+	state := newSyncState()
+	server := syncServer(t, state)
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(server.URL)})
+	syncer := lib.NewSubscriberSync(c, lib.SubscriberSyncOptions{Prune: true, MaxDeletes: -1, DryRun: true})
+
+	plan, err := syncer.Plan(context.Background(), desiredSubscribers(lib.SubscriberPayload{SubscriberId: "dave"}))
+	require.NoError(t, err)
+	assert.Len(t, plan.Deletes, 3)
+
+	result, err := syncer.Apply(context.Background(), plan)
+	require.NoError(t, err)
+
+	assert.True(t, result.DryRun)
+	assert.Zero(t, state.bulk)
+	assert.Empty(t, state.updates)
+	assert.Empty(t, state.deletes)
+}
+
+func TestSubscriberSync_Apply_MaxDeletes(t *testing.T) {
+	state := newSyncState()
+	server := syncServer(t, state)
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(server.URL)})
+	syncer := lib.NewSubscriberSync(c, lib.SubscriberSyncOptions{Prune: true, MaxDeletes: 2})
+
+	plan, err := syncer.Plan(context.Background(), desiredSubscribers())
+	require.NoError(t, err)
+
+	_, err = syncer.Apply(context.Background(), plan)
+	require.Error(t, err)
+	assert.Empty(t, state.deletes)
+}
+
+func TestSubscriberSync_Apply_CreatedSincePlan(t *testing.T) {
+	state := newSyncState()
+	server := syncServer(t, state)
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(server.URL)})
+	syncer := lib.NewSubscriberSync(c, lib.SubscriberSyncOptions{})
+
+	plan, err := syncer.Plan(context.Background(), desiredSubscribers(
+		lib.SubscriberPayload{SubscriberId: "dave"},
+		lib.SubscriberPayload{SubscriberId: "erin"},
+	))
+	require.NoError(t, err)
+	require.Len(t, plan.Creates, 2)
+
+	state.existing = map[string]bool{"erin": true}
+	result, err := syncer.Apply(context.Background(), plan)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"dave"}, result.Created)
+	assert.Equal(t, []string{"erin"}, result.Updated, "bulk create updated the subscriber created since the plan")
+}
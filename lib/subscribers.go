@@ -3,27 +3,60 @@ package lib
 import (
 	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"io"
 	"net/http"
-	"strconv"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
 )
 
+const (
+	// DefaultSubscribersPageSize is the page size used when walking every
+	// subscriber of an environment.
+	DefaultSubscribersPageSize = 100
+
+	// MaxBulkSubscribers is the number of subscribers Novu accepts in a single
+	// bulk create request.
+	MaxBulkSubscribers = 500
+)
+
+// ErrSubscriberSearchUnsupported is returned by SubscriberService.Find when the
+// server ignored the search filters and no local index was given.
+var ErrSubscriberSearchUnsupported = errors.New("subscriber search is not supported by the server")
+
 type ISubscribers interface {
 	Identify(ctx context.Context, subscriberID string, data interface{}) (SubscriberResponse, error)
 	BulkCreate(ctx context.Context, subscribers SubscriberBulkPayload) (SubscriberBulkCreateResponse, error)
+	Import(ctx context.Context, r io.Reader, opts SubscriberImportOptions) (*SubscriberImportReport, error)
 	Get(ctx context.Context, subscriberID string) (SubscriberResponse, error)
+	List(ctx context.Context, opts *ListSubscribersOptions) (*ListSubscribersResponse, error)
+	ListAll(ctx context.Context, pageSize int, fn func(Subscriber) error) error
+	Find(ctx context.Context, lookup SubscriberLookup, index *SubscriberIndex) ([]Subscriber, error)
+	Export(ctx context.Context, w io.Writer, opts SubscriberExportOptions) (SubscriberExportCheckpoint, error)
 	Update(ctx context.Context, subscriberID string, data interface{}) (SubscriberResponse, error)
+	UpdateOnlineStatus(ctx context.Context, subscriberID string, online bool) (SubscriberResponse, error)
 	UpdateCredentials(ctx context.Context, subscriberID string, payload SubscriberCredentialPayload) (SubscriberResponse, error)
-	Delete(ctx context.Context, subscriberID string) (SubscriberResponse, error)
+	DeleteCredentials(ctx context.Context, subscriberID string, providerId ProviderIdType) error
+	AddDeviceToken(ctx context.Context, subscriberID string, providerId ProviderIdType, token string, opts *DeviceTokenOptions) ([]string, error)
+	RemoveDeviceToken(ctx context.Context, subscriberID string, providerId ProviderIdType, token string, opts *DeviceTokenOptions) ([]string, error)
+	Delete(ctx context.Context, subscriberID string) (DeleteSubscriberResponse, error)
+	EraseSubscriber(ctx context.Context, subscriberID string, opts *SubscriberErasureOptions) (*SubscriberErasureReport, error)
+	MergeSubscribers(ctx context.Context, targetID string, sourceID string, opts *SubscriberMergeOptions) (*SubscriberMergePlan, error)
 	GetNotificationFeed(ctx context.Context, subscriberID string, opts *SubscriberNotificationFeedOptions) (*SubscriberNotificationFeedResponse, error)
 	GetUnseenCount(ctx context.Context, subscriberID string, opts *SubscriberUnseenCountOptions) (*SubscriberUnseenCountResponse, error)
 	MarkMessageSeen(ctx context.Context, subscriberID string, opts SubscriberMarkMessageSeenOptions) (*SubscriberNotificationFeedResponse, error)
+	MarkMessages(ctx context.Context, subscriberID string, opts SubscriberMarkMessagesOptions) ([]NotificationFeedData, error)
+	MarkAllMessages(ctx context.Context, subscriberID string, markAs MarkAs, feedIdentifier string) (int, error)
+	UpdateMessageAction(ctx context.Context, subscriberID string, messageID string, buttonType ButtonType, opts MessageActionOptions) (*NotificationFeedData, error)
 	GetPreferences(ctx context.Context, subscriberID string) (*SubscriberPreferencesResponse, error)
 	UpdatePreferences(ctx context.Context, subscriberID string, templateId string, opts *UpdateSubscriberPreferencesOptions) (*UpdateSubscriberPreferencesResponse, error)
+	UpdatePreferencesBulk(ctx context.Context, subscriberID string, updates []WorkflowPreferenceUpdate) (*PreferenceBulkUpdateReport, error)
+	GetGlobalPreferences(ctx context.Context, subscriberID string) (*Preference, error)
+	UpdateGlobalPreferences(ctx context.Context, subscriberID string, opts UpdateGlobalPreferencesOptions) (*Preference, error)
+	GetPreferenceMatrix(ctx context.Context, subscriberID string) (*PreferenceMatrix, error)
 }
 
 type SubscriberService service
@@ -54,6 +87,10 @@ func (s *SubscriberService) Identify(ctx context.Context, subscriberID string, d
 
 func (s *SubscriberService) BulkCreate(ctx context.Context, subscribers SubscriberBulkPayload) (SubscriberBulkCreateResponse, error) {
 	var resp SubscriberBulkCreateResponse
+	if len(subscribers.Subscribers) > MaxBulkSubscribers {
+		return resp, errors.Errorf("bulk create accepts at most %d subscribers, got %d", MaxBulkSubscribers, len(subscribers.Subscribers))
+	}
+
 	URL := s.client.config.BackendURL.JoinPath("subscribers", "bulk")
 	jsonBody, err := json.Marshal(subscribers)
 	if err != nil {
@@ -89,6 +126,215 @@ func (s *SubscriberService) Get(ctx context.Context, subscriberID string) (Subsc
 	return resp, nil
 }
 
+func (s *SubscriberService) List(ctx context.Context, opts *ListSubscribersOptions) (*ListSubscribersResponse, error) {
+	var resp ListSubscribersResponse
+	URL := s.client.config.BackendURL.JoinPath("subscribers")
+
+	query, err := EncodeQuery(opts)
+	if err != nil {
+		return nil, err
+	}
+	URL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, URL.String(), http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.client.sendRequest(req, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// ListAll pages through every subscriber of the environment and calls fn for
+// each of them, stopping at the first error.
+func (s *SubscriberService) ListAll(ctx context.Context, pageSize int, fn func(Subscriber) error) error {
+	if pageSize <= 0 {
+		pageSize = DefaultSubscribersPageSize
+	}
+
+	for page := 0; ; page++ {
+		p := page
+		resp, err := s.List(ctx, &ListSubscribersOptions{Page: &p, Limit: &pageSize})
+		if err != nil {
+			return errors.Wrapf(err, "unable to list subscribers page %d", page)
+		}
+
+		for _, subscriber := range resp.Data {
+			if err := fn(subscriber); err != nil {
+				return err
+			}
+		}
+
+		if len(resp.Data) == 0 || (!resp.HasMore && len(resp.Data) < pageSize) {
+			return nil
+		}
+	}
+}
+
+// Find returns the subscribers matching lookup. The server search is tried
+// first; when the server ignores the filters the lookup falls back to index,
+// and fails with ErrSubscriberSearchUnsupported when index is nil.
+func (s *SubscriberService) Find(ctx context.Context, lookup SubscriberLookup, index *SubscriberIndex) ([]Subscriber, error) {
+	if lookup.Email == "" && lookup.Phone == "" {
+		return nil, errors.New("lookup needs an email or a phone")
+	}
+
+	limit := DefaultSubscribersPageSize
+	resp, err := s.List(ctx, &ListSubscribersOptions{Limit: &limit, Email: lookup.Email, Phone: lookup.Phone})
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]Subscriber, 0)
+	for _, subscriber := range resp.Data {
+		if !lookup.matches(subscriber) {
+			// the server returned the unfiltered list
+			if index == nil {
+				return nil, ErrSubscriberSearchUnsupported
+			}
+			return index.Find(lookup), nil
+		}
+		matches = append(matches, subscriber)
+	}
+
+	return matches, nil
+}
+
+func (l SubscriberLookup) matches(subscriber Subscriber) bool {
+	if l.Email != "" && normalizeEmail(l.Email) != normalizeEmail(subscriber.Email) {
+		return false
+	}
+	if l.Phone != "" && normalizePhone(l.Phone) != normalizePhone(subscriber.Phone) {
+		return false
+	}
+
+	return true
+}
+
+// SubscriberIndex is a local email and phone index of subscribers, used to
+// resolve lookups against servers without subscriber search.
+type SubscriberIndex struct {
+	mu      sync.RWMutex
+	byID    map[string]Subscriber
+	byEmail map[string]map[string]bool
+	byPhone map[string]map[string]bool
+}
+
+func NewSubscriberIndex(subscribers ...Subscriber) *SubscriberIndex {
+	index := &SubscriberIndex{
+		byID:    make(map[string]Subscriber),
+		byEmail: make(map[string]map[string]bool),
+		byPhone: make(map[string]map[string]bool),
+	}
+	for _, subscriber := range subscribers {
+		index.Add(subscriber)
+	}
+
+	return index
+}
+
+// BuildSubscriberIndex indexes every subscriber of the environment.
+func BuildSubscriberIndex(ctx context.Context, s ISubscribers, pageSize int) (*SubscriberIndex, error) {
+	index := NewSubscriberIndex()
+	err := s.ListAll(ctx, pageSize, func(subscriber Subscriber) error {
+		index.Add(subscriber)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return index, nil
+}
+
+// Add indexes the subscriber, replacing a previously indexed version of it.
+func (i *SubscriberIndex) Add(subscriber Subscriber) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.remove(subscriber.SubscriberId)
+	i.byID[subscriber.SubscriberId] = subscriber
+	addIndexKey(i.byEmail, normalizeEmail(subscriber.Email), subscriber.SubscriberId)
+	addIndexKey(i.byPhone, normalizePhone(subscriber.Phone), subscriber.SubscriberId)
+}
+
+func (i *SubscriberIndex) Remove(subscriberID string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.remove(subscriberID)
+}
+
+func (i *SubscriberIndex) remove(subscriberID string) {
+	previous, ok := i.byID[subscriberID]
+	if !ok {
+		return
+	}
+
+	delete(i.byID, subscriberID)
+	removeIndexKey(i.byEmail, normalizeEmail(previous.Email), subscriberID)
+	removeIndexKey(i.byPhone, normalizePhone(previous.Phone), subscriberID)
+}
+
+// Find returns the indexed subscribers matching lookup.
+func (i *SubscriberIndex) Find(lookup SubscriberLookup) []Subscriber {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	candidates := i.byEmail[normalizeEmail(lookup.Email)]
+	if lookup.Email == "" {
+		candidates = i.byPhone[normalizePhone(lookup.Phone)]
+	}
+
+	matches := make([]Subscriber, 0, len(candidates))
+	for id := range candidates {
+		if subscriber := i.byID[id]; lookup.matches(subscriber) {
+			matches = append(matches, subscriber)
+		}
+	}
+	sort.Slice(matches, func(a, b int) bool {
+		return matches[a].SubscriberId < matches[b].SubscriberId
+	})
+
+	return matches
+}
+
+func addIndexKey(index map[string]map[string]bool, key string, subscriberID string) {
+	if key == "" {
+		return
+	}
+	if index[key] == nil {
+		index[key] = make(map[string]bool)
+	}
+	index[key][subscriberID] = true
+}
+
+func removeIndexKey(index map[string]map[string]bool, key string, subscriberID string) {
+	delete(index[key], subscriberID)
+	if len(index[key]) == 0 {
+		delete(index, key)
+	}
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func normalizePhone(phone string) string {
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(phone) {
+		if (r >= '0' && r <= '9') || (r == '+' && i == 0) {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
 func (s *SubscriberService) Update(ctx context.Context, subscriberID string, data interface{}) (SubscriberResponse, error) {
 	var resp SubscriberResponse
 	URL := s.client.config.BackendURL.JoinPath("subscribers", subscriberID)
@@ -108,6 +354,26 @@ func (s *SubscriberService) Update(ctx context.Context, subscriberID string, dat
 	return resp, nil
 }
 
+// UpdateOnlineStatus sets the isOnline flag of the subscriber.
+func (s *SubscriberService) UpdateOnlineStatus(ctx context.Context, subscriberID string, online bool) (SubscriberResponse, error) {
+	var resp SubscriberResponse
+	URL := s.client.config.BackendURL.JoinPath("subscribers", subscriberID, "online-status")
+
+	jsonBody, _ := json.Marshal(UpdateOnlineStatusPayload{IsOnline: online})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, URL.String(), bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return resp, err
+	}
+
+	_, err = s.client.sendRequest(req, &resp)
+	if err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
 func (s *SubscriberService) UpdateCredentials(ctx context.Context, subscriberID string, data SubscriberCredentialPayload) (SubscriberResponse, error) {
 	var resp SubscriberResponse
 	URL := s.client.config.BackendURL.JoinPath("subscribers", subscriberID, "credentials")
@@ -127,8 +393,26 @@ func (s *SubscriberService) UpdateCredentials(ctx context.Context, subscriberID
 	return resp, nil
 }
 
-func (s *SubscriberService) Delete(ctx context.Context, subscriberID string) (SubscriberResponse, error) {
-	var resp SubscriberResponse
+// DeleteCredentials removes the credentials of a provider from the subscriber.
+func (s *SubscriberService) DeleteCredentials(ctx context.Context, subscriberID string, providerId ProviderIdType) error {
+	var resp interface{}
+	URL := s.client.config.BackendURL.JoinPath("subscribers", subscriberID, "credentials", string(providerId))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, URL.String(), http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.sendRequest(req, &resp)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *SubscriberService) Delete(ctx context.Context, subscriberID string) (DeleteSubscriberResponse, error) {
+	var resp DeleteSubscriberResponse
 	URL := s.client.config.BackendURL.JoinPath("subscribers", subscriberID)
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, URL.String(), http.NoBody)
@@ -148,37 +432,11 @@ func (s *SubscriberService) GetNotificationFeed(ctx context.Context, subscriberI
 	var resp SubscriberNotificationFeedResponse
 	URL := s.client.config.BackendURL.JoinPath("subscribers", subscriberID, "notifications", "feed")
 
-	if opts != nil {
-		queryValues := URL.Query()
-		if opts.Payload != nil {
-			var payloadOpts Base64Payload
-			payloadString, err := json.Marshal(opts.Payload)
-			if err != nil {
-				return nil, err
-			}
-			opts.Payload = nil
-
-			payloadOpts.Payload = base64.StdEncoding.EncodeToString(payloadString)
-			params, err := GenerateQueryParamsFromStruct(payloadOpts)
-			if err != nil {
-				return nil, err
-			}
-			for _, param := range params {
-				queryValues.Add(param.Key, param.Value)
-			}
-		}
-
-		params, err := GenerateQueryParamsFromStruct(*opts)
-		if err != nil {
-			return nil, err
-		}
-
-		for _, param := range params {
-			queryValues.Add(param.Key, param.Value)
-		}
-
-		URL.RawQuery = queryValues.Encode()
+	query, err := EncodeQuery(opts)
+	if err != nil {
+		return nil, err
 	}
+	URL.RawQuery = query.Encode()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, URL.String(), http.NoBody)
 	if err != nil {
@@ -213,13 +471,11 @@ func (s *SubscriberService) GetUnseenCount(ctx context.Context, subscriberID str
 	var resp SubscriberUnseenCountResponse
 	URL := s.client.config.BackendURL.JoinPath("subscribers", subscriberID, "notifications", "unseen")
 
-	if opts != nil {
-		if opts.Seen != nil {
-			queryValues := URL.Query()
-			queryValues.Add("seen", strconv.FormatBool(*opts.Seen))
-			URL.RawQuery = queryValues.Encode()
-		}
+	query, err := EncodeQuery(opts)
+	if err != nil {
+		return nil, err
 	}
+	URL.RawQuery = query.Encode()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, URL.String(), http.NoBody)
 	if err != nil {
@@ -284,4 +540,76 @@ func (s *SubscriberService) MarkMessageSeen(ctx context.Context, subscriberID st
 	return &resp, nil
 }
 
+// MarkMessages sets the seen and read flags of several messages at once and
+// returns the updated messages.
+func (s *SubscriberService) MarkMessages(ctx context.Context, subscriberID string, opts SubscriberMarkMessagesOptions) ([]NotificationFeedData, error) {
+	var resp MarkMessagesResponse
+	URL := s.client.config.BackendURL.JoinPath("subscribers", subscriberID, "messages", "markAs")
+
+	jsonBody, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, URL.String(), bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.client.sendRequest(req, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Data, nil
+}
+
+// MarkAllMessages marks every message of the subscriber, or of a single feed
+// when feedIdentifier is set, and returns the number of messages updated.
+func (s *SubscriberService) MarkAllMessages(ctx context.Context, subscriberID string, markAs MarkAs, feedIdentifier string) (int, error) {
+	var resp MarkAllMessagesResponse
+	URL := s.client.config.BackendURL.JoinPath("subscribers", subscriberID, "messages", "mark-all")
+
+	jsonBody, err := json.Marshal(MarkAllMessagesOptions{MarkAs: markAs, FeedIdentifier: feedIdentifier})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, URL.String(), bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = s.client.sendRequest(req, &resp)
+	if err != nil {
+		return 0, err
+	}
+
+	return resp.Data, nil
+}
+
+// UpdateMessageAction records a click on a CTA button of an in-app message and
+// returns the message with its updated action status and result.
+func (s *SubscriberService) UpdateMessageAction(ctx context.Context, subscriberID string, messageID string, buttonType ButtonType, opts MessageActionOptions) (*NotificationFeedData, error) {
+	var resp MessageActionResponse
+	URL := s.client.config.BackendURL.JoinPath("subscribers", subscriberID, "messages", messageID, "actions", string(buttonType))
+
+	jsonBody, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, URL.String(), bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.client.sendRequest(req, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp.Data, nil
+}
+
 var _ ISubscribers = &SubscriberService{}
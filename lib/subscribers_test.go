@@ -109,7 +109,7 @@ func TestSubscriberService_BulkCreate_Success(t *testing.T) {
 			assert.Equal(t, expectedRequest, receivedBody)
 		})
 
-		var resp lib.SubscriberResponse
+		var resp lib.SubscriberBulkCreateResponse
 		fileToStruct(filepath.Join("../testdata", "subscriber_bulk_response.json"), &resp)
 
 		w.WriteHeader(http.StatusOK)
@@ -244,8 +244,43 @@ func TestSubscriberService_Update_Credentials_Success(t *testing.T) {
 	})
 }
 
-func TestSubscriberService_Delete_Success(t *testing.T) {
+func TestSubscriberService_UpdateOnlineStatus_Success(t *testing.T) {
 	var expectedResponse lib.SubscriberResponse
+	fileToStruct(filepath.Join("../testdata", "subscriber_response.json"), &expectedResponse)
+
+	httpServer := createTestServer(t, TestServerOptions[lib.UpdateOnlineStatusPayload, lib.SubscriberResponse]{
+		expectedURLPath:    "/v1/subscribers/" + subscriberID + "/online-status",
+		expectedSentMethod: http.MethodPatch,
+		expectedSentBody:   lib.UpdateOnlineStatusPayload{IsOnline: true},
+		responseStatusCode: http.StatusOK,
+		responseBody:       expectedResponse,
+	})
+
+	ctx := context.Background()
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+	resp, err := c.SubscriberApi.UpdateOnlineStatus(ctx, subscriberID, true)
+
+	require.NoError(t, err)
+	require.Equal(t, expectedResponse, resp)
+}
+
+func TestSubscriberService_DeleteCredentials_Success(t *testing.T) {
+	httpServer := createTestServer(t, TestServerOptions[io.Reader, interface{}]{
+		expectedURLPath:    "/v1/subscribers/" + subscriberID + "/credentials/" + string(lib.ProviderFCM),
+		expectedSentMethod: http.MethodDelete,
+		expectedSentBody:   http.NoBody,
+		responseStatusCode: http.StatusNoContent,
+	})
+
+	ctx := context.Background()
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+	err := c.SubscriberApi.DeleteCredentials(ctx, subscriberID, lib.ProviderFCM)
+
+	require.NoError(t, err)
+}
+
+func TestSubscriberService_Delete_Success(t *testing.T) {
+	var expectedResponse lib.DeleteSubscriberResponse
 
 	ctx := context.Background()
 
@@ -262,8 +297,8 @@ func TestSubscriberService_Delete_Success(t *testing.T) {
 			assert.Equal(t, expectedURL, req.RequestURI)
 		})
 
-		var resp lib.SubscriberResponse
-		fileToStruct(filepath.Join("../testdata", "subscriber_response.json"), &resp)
+		var resp lib.DeleteSubscriberResponse
+		fileToStruct(filepath.Join("../testdata", "delete_subscriber_response.json"), &resp)
 
 		w.WriteHeader(http.StatusOK)
 		bb, _ := json.Marshal(resp)
@@ -277,7 +312,7 @@ func TestSubscriberService_Delete_Success(t *testing.T) {
 	assert.NotNil(t, resp)
 
 	t.Run("Response is as expected", func(t *testing.T) {
-		fileToStruct(filepath.Join("../testdata", "subscriber_response.json"), &expectedResponse)
+		fileToStruct(filepath.Join("../testdata", "delete_subscriber_response.json"), &expectedResponse)
 		assert.Equal(t, expectedResponse, resp)
 	})
 }
@@ -295,7 +330,7 @@ func TestSubscriberService_GetNotificationFeed_Success(t *testing.T) {
 
 	opts := lib.SubscriberNotificationFeedOptions{
 		Page:           page,
-		Seen:           seen,
+		Seen:           &seen,
 		FeedIdentifier: feedIdentifier,
 		Payload:        payload,
 	}
@@ -334,6 +369,16 @@ func TestSubscriberService_GetSubscriber_Success(t *testing.T) {
 
 	require.NoError(t, err)
 	require.Equal(t, resp, expectedResponse)
+
+	t.Run("Subscriber is typed", func(t *testing.T) {
+		assert.Equal(t, subscriberID, resp.Data.SubscriberId)
+		assert.Equal(t, "pro", resp.Data.Data["plan"])
+		assert.True(t, resp.Data.IsOnline)
+		require.NotNil(t, resp.Data.LastOnlineAt)
+		require.Len(t, resp.Data.Channels, 2)
+		assert.Equal(t, lib.ProviderIdType("fcm"), resp.Data.Channels[0].ProviderId)
+		assert.Equal(t, []string{"token1", "token2"}, resp.Data.Channels[0].Credentials.DeviceTokens)
+	})
 }
 
 func TestSubscriberService_GetPreferences_Success(t *testing.T) {
@@ -407,11 +452,80 @@ func TestSubscriberService_MarkMessageSeen(t *testing.T) {
 	require.Equal(t, resp, expectedResponse)
 }
 
+func TestSubscriberService_MarkMessages(t *testing.T) {
+	var expectedResponse lib.MarkMessagesResponse
+	fileToStruct(filepath.Join("../testdata", "subscriber_notification_feed_response.json"), &expectedResponse)
+
+	seen := true
+	opts := lib.SubscriberMarkMessagesOptions{
+		MessageIDs: []string{"message_1", "message_2"},
+		Mark:       lib.MessageMark{Seen: &seen},
+	}
+
+	httpServer := createTestServer(t, TestServerOptions[lib.SubscriberMarkMessagesOptions, lib.MarkMessagesResponse]{
+		expectedURLPath:    fmt.Sprintf("/v1/subscribers/%s/messages/markAs", subscriberID),
+		expectedSentMethod: http.MethodPost,
+		expectedSentBody:   opts,
+		responseStatusCode: http.StatusCreated,
+		responseBody:       expectedResponse,
+	})
+
+	ctx := context.Background()
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+	resp, err := c.SubscriberApi.MarkMessages(ctx, subscriberID, opts)
+	require.NoError(t, err)
+	require.Equal(t, expectedResponse.Data, resp)
+}
+
+func TestSubscriberService_MarkAllMessages(t *testing.T) {
+	httpServer := createTestServer(t, TestServerOptions[lib.MarkAllMessagesOptions, lib.MarkAllMessagesResponse]{
+		expectedURLPath:    fmt.Sprintf("/v1/subscribers/%s/messages/mark-all", subscriberID),
+		expectedSentMethod: http.MethodPost,
+		expectedSentBody:   lib.MarkAllMessagesOptions{MarkAs: lib.MarkAsRead, FeedIdentifier: "updates"},
+		responseStatusCode: http.StatusCreated,
+		responseBody:       lib.MarkAllMessagesResponse{Data: 3},
+	})
+
+	ctx := context.Background()
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+	count, err := c.SubscriberApi.MarkAllMessages(ctx, subscriberID, lib.MarkAsRead, "updates")
+	require.NoError(t, err)
+	require.Equal(t, 3, count)
+}
+
+func TestSubscriberService_UpdateMessageAction(t *testing.T) {
+	var expectedResponse lib.MessageActionResponse
+	fileToStruct(filepath.Join("../testdata", "message_action_response.json"), &expectedResponse)
+
+	opts := lib.MessageActionOptions{
+		Status:  lib.MessageActionDone,
+		Payload: map[string]interface{}{"inviteId": "inv_1"},
+	}
+
+	httpServer := createTestServer(t, TestServerOptions[lib.MessageActionOptions, lib.MessageActionResponse]{
+		expectedURLPath:    fmt.Sprintf("/v1/subscribers/%s/messages/%s/actions/primary", subscriberID, "message_id"),
+		expectedSentMethod: http.MethodPost,
+		expectedSentBody:   opts,
+		responseStatusCode: http.StatusCreated,
+		responseBody:       expectedResponse,
+	})
+
+	ctx := context.Background()
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+	message, err := c.SubscriberApi.UpdateMessageAction(ctx, subscriberID, "message_id", lib.ButtonPrimary, opts)
+	require.NoError(t, err)
+
+	require.Equal(t, &expectedResponse.Data, message)
+	assert.Equal(t, lib.MessageActionDone, message.CTA.Action.Status)
+	assert.Equal(t, lib.ButtonPrimary, message.CTA.Action.Result.Type)
+	assert.Equal(t, "inv_1", message.CTA.Action.Result.Payload["inviteId"])
+}
+
 func TestSubscriberService_UpdatePreferences_Success(t *testing.T) {
 	var topicID = "topicId"
 
-	var expectedResponse *lib.SubscriberPreferencesResponse
-	fileToStruct(filepath.Join("../testdata", "subscriber_preferences_response.json"), &expectedResponse)
+	var expectedResponse *lib.UpdateSubscriberPreferencesResponse
+	fileToStruct(filepath.Join("../testdata", "update_subscriber_preferences_response.json"), &expectedResponse)
 	enabled := true
 	var opts *lib.UpdateSubscriberPreferencesOptions = &lib.UpdateSubscriberPreferencesOptions{
 		Enabled: &enabled,
@@ -420,7 +534,7 @@ func TestSubscriberService_UpdatePreferences_Success(t *testing.T) {
 				Enabled: true,
 		},
 	}
-	httpServer := createTestServer(t, TestServerOptions[*lib.UpdateSubscriberPreferencesOptions, *lib.SubscriberPreferencesResponse]{
+	httpServer := createTestServer(t, TestServerOptions[*lib.UpdateSubscriberPreferencesOptions, *lib.UpdateSubscriberPreferencesResponse]{
 		expectedURLPath:    fmt.Sprintf("/v1/subscribers/%s/preferences/%s", subscriberID, topicID),
 		expectedSentMethod: http.MethodPatch,
 		expectedSentBody:   opts,
@@ -435,3 +549,112 @@ func TestSubscriberService_UpdatePreferences_Success(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, resp, expectedResponse)
 }
+
+var listedSubscribers = []lib.Subscriber{
+	{SubscriberId: "alice", Email: "alice@example.com", Phone: "+1 (555) 0100"},
+	{SubscriberId: "bob", Email: "Bob@Example.com"},
+	{SubscriberId: "carol", Phone: "+15550102"},
+}
+
+func TestSubscriberService_List_Success(t *testing.T) {
+	expectedResponse := &lib.ListSubscribersResponse{
+		Page:     1,
+		PageSize: 2,
+		HasMore:  true,
+		Data:     listedSubscribers[:2],
+	}
+
+	httpServer := createTestServer(t, TestServerOptions[io.Reader, *lib.ListSubscribersResponse]{
+		expectedURLPath:    "/v1/subscribers?limit=2&page=1",
+		expectedSentMethod: http.MethodGet,
+		expectedSentBody:   http.NoBody,
+		responseStatusCode: http.StatusOK,
+		responseBody:       expectedResponse,
+	})
+
+	page, limit := 1, 2
+	ctx := context.Background()
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+	resp, err := c.SubscriberApi.List(ctx, &lib.ListSubscribersOptions{Page: &page, Limit: &limit})
+
+	require.NoError(t, err)
+	require.Equal(t, expectedResponse, resp)
+}
+
+// listSubscribersServer serves listedSubscribers in pages of two. When search
+// is true the email and phone filters are honoured.
+func listSubscribersServer(t *testing.T, search bool) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodGet, req.Method)
+		assert.Equal(t, "/v1/subscribers", req.URL.Path)
+
+		query := req.URL.Query()
+		page, _ := strconv.Atoi(query.Get("page"))
+		limit, _ := strconv.Atoi(query.Get("limit"))
+
+		data := listedSubscribers
+		if search && (query.Get("email") != "" || query.Get("phone") != "") {
+			data = nil
+			for _, subscriber := range listedSubscribers {
+				if strings.EqualFold(subscriber.Email, query.Get("email")) {
+					data = append(data, subscriber)
+				}
+			}
+		}
+
+		start, end := page*limit, page*limit+limit
+		if start > len(data) {
+			start = len(data)
+		}
+		if end > len(data) {
+			end = len(data)
+		}
+
+		bb, _ := json.Marshal(lib.ListSubscribersResponse{
+			Page:     page,
+			PageSize: limit,
+			HasMore:  end < len(data),
+			Data:     data[start:end],
+		})
+		w.WriteHeader(http.StatusOK)
+		w.Write(bb)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestSubscriberService_Find(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Server side search", func(t *testing.T) {
+		server := listSubscribersServer(t, true)
+		c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(server.URL)})
+
+		found, err := c.SubscriberApi.Find(ctx, lib.SubscriberLookup{Email: "bob@example.com"}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []lib.Subscriber{listedSubscribers[1]}, found)
+	})
+
+	t.Run("Local index fallback", func(t *testing.T) {
+		server := listSubscribersServer(t, false)
+		c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(server.URL)})
+
+		_, err := c.SubscriberApi.Find(ctx, lib.SubscriberLookup{Email: "bob@example.com"}, nil)
+		require.ErrorIs(t, err, lib.ErrSubscriberSearchUnsupported)
+
+		index, err := lib.BuildSubscriberIndex(ctx, c.SubscriberApi, 2)
+		require.NoError(t, err)
+
+		found, err := c.SubscriberApi.Find(ctx, lib.SubscriberLookup{Email: " BOB@example.com"}, index)
+		require.NoError(t, err)
+		assert.Equal(t, []lib.Subscriber{listedSubscribers[1]}, found)
+
+		found, err = c.SubscriberApi.Find(ctx, lib.SubscriberLookup{Phone: "+15550100"}, index)
+		require.NoError(t, err)
+		assert.Equal(t, []lib.Subscriber{listedSubscribers[0]}, found)
+
+		index.Remove("alice")
+		assert.Empty(t, index.Find(lib.SubscriberLookup{Phone: "+15550100"}))
+	})
+}
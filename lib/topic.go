@@ -12,6 +12,7 @@ import (
 type ITopic interface {
 	Create(ctx context.Context, key string, name string) error
 	List(ctx context.Context, options *ListTopicsOptions) (*ListTopicsResponse, error)
+	ListAll(ctx context.Context, fn func(GetTopicResponse) error) error
 	CheckTopicSubscriber(ctx context.Context, key string, externalsubscriber string) (*CheckTopicSubscriberResponse, error)
 	AddSubscribers(ctx context.Context, key string, subscribers []string) error
 	RemoveSubscribers(ctx context.Context, key string, subscribers []string) error
@@ -20,6 +21,9 @@ type ITopic interface {
 	Delete(ctx context.Context, key string) error
 }
 
+// DefaultTopicsPageSize is the page size used by TopicService.ListAll.
+const DefaultTopicsPageSize = 100
+
 type TopicService service
 
 func (t *TopicService) Create(ctx context.Context, key string, name string) error {
@@ -54,12 +58,13 @@ func (t *TopicService) List(ctx context.Context, options *ListTopicsOptions) (*L
 	var resp ListTopicsResponse
 	URL := t.client.config.BackendURL.JoinPath("topics")
 
-	if options == nil {
-		options = &ListTopicsOptions{}
+	query, err := EncodeQuery(options)
+	if err != nil {
+		return nil, err
 	}
-	queryParams, _ := json.Marshal(options)
+	URL.RawQuery = query.Encode()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, URL.String(), bytes.NewBuffer(queryParams))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, URL.String(), http.NoBody)
 	if err != nil {
 		return nil, err
 	}
@@ -72,6 +77,32 @@ func (t *TopicService) List(ctx context.Context, options *ListTopicsOptions) (*L
 	return &resp, nil
 }
 
+// ListAll pages through every topic of the environment and calls fn for each of
+// them, stopping at the first error.
+func (t *TopicService) ListAll(ctx context.Context, fn func(GetTopicResponse) error) error {
+	pageSize := DefaultTopicsPageSize
+	listed := 0
+
+	for page := 0; ; page++ {
+		p := page
+		resp, err := t.List(ctx, &ListTopicsOptions{Page: &p, PageSize: &pageSize})
+		if err != nil {
+			return errors.Wrapf(err, "unable to list topics page %d", page)
+		}
+
+		for _, topic := range resp.Data {
+			if err := fn(topic); err != nil {
+				return err
+			}
+		}
+		listed += len(resp.Data)
+
+		if len(resp.Data) < pageSize || (resp.TotalCount > 0 && listed >= resp.TotalCount) {
+			return nil
+		}
+	}
+}
+
 func (t *TopicService) CheckTopicSubscriber(ctx context.Context, key string, externalsubscriber string) (*CheckTopicSubscriberResponse, error) {
 	var resp CheckTopicSubscriberResponse
 	URL := t.client.config.BackendURL.JoinPath("topics", key, "subscribers", externalsubscriber)
@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -213,6 +214,71 @@ func TestListTopics_Success(t *testing.T) {
 	require.Equal(t, resp, expectedResponse)
 }
 
+func TestListTopics_Options(t *testing.T) {
+	page, pageSize, key := 2, 5, "topicKey"
+	httpServer := createTestServer(t, TestServerOptions[map[string]string, *lib.ListTopicsResponse]{
+		expectedURLPath:    "/v1/topics?key=topicKey&page=2&pageSize=5",
+		expectedSentMethod: http.MethodGet,
+		responseStatusCode: http.StatusOK,
+		responseBody:       &lib.ListTopicsResponse{Page: page, PageSize: pageSize},
+	})
+
+	ctx := context.Background()
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+	_, err := c.TopicsApi.List(ctx, &lib.ListTopicsOptions{Page: &page, PageSize: &pageSize, Key: &key})
+
+	require.NoError(t, err)
+}
+
+// listTopicsPage answers a topic listing with the page of topics asked for,
+// 10 topics per page unless pageSize is set, like Novu does.
+func listTopicsPage(req *http.Request, topics []lib.GetTopicResponse) lib.ListTopicsResponse {
+	page, _ := strconv.Atoi(req.URL.Query().Get("page"))
+	pageSize, err := strconv.Atoi(req.URL.Query().Get("pageSize"))
+	if err != nil {
+		pageSize = 10
+	}
+
+	start, end := page*pageSize, page*pageSize+pageSize
+	if start > len(topics) {
+		start = len(topics)
+	}
+	if end > len(topics) {
+		end = len(topics)
+	}
+
+	return lib.ListTopicsResponse{Page: page, PageSize: pageSize, TotalCount: len(topics), Data: topics[start:end]}
+}
+
+func TestListAllTopics_Pages(t *testing.T) {
+	var topics []lib.GetTopicResponse
+	for i := 0; i < 250; i++ {
+		topics = append(topics, lib.GetTopicResponse{Key: fmt.Sprintf("topic-%d", i)})
+	}
+
+	requests := 0
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		bb, _ := json.Marshal(listTopicsPage(req, topics))
+		w.Write(bb)
+	}))
+	defer httpServer.Close()
+
+	ctx := context.Background()
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(httpServer.URL)})
+
+	var keys []string
+	err := c.TopicsApi.ListAll(ctx, func(topic lib.GetTopicResponse) error {
+		keys = append(keys, topic.Key)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Len(t, keys, len(topics))
+	assert.Equal(t, "topic-249", keys[249])
+	assert.Equal(t, 3, requests)
+}
+
 func TestRenameTopic_Success(t *testing.T) {
 	topicKey := "topicKey"
 	newName := "topicName"
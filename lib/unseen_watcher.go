@@ -0,0 +1,402 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultUnseenMinInterval     = 5 * time.Second
+	DefaultUnseenMaxInterval     = 2 * time.Minute
+	DefaultUnseenBackoff         = 2.0
+	DefaultUnseenPollConcurrency = 4
+	DefaultUnseenStreamKeepAlive = 15 * time.Second
+	unseenCountStreamEvent       = "unseen_count"
+)
+
+// UnseenCountWatcherOptions configures an UnseenCountWatcher.
+type UnseenCountWatcherOptions struct {
+	// MinInterval is the polling interval of a subscriber right after its
+	// count changed. Defaults to DefaultUnseenMinInterval.
+	MinInterval time.Duration
+
+	// MaxInterval caps the polling interval of an idle subscriber. Defaults
+	// to DefaultUnseenMaxInterval.
+	MaxInterval time.Duration
+
+	// Backoff multiplies the interval after every poll that saw no change.
+	// Defaults to DefaultUnseenBackoff.
+	Backoff float64
+
+	// Concurrency is the number of GetUnseenCount calls in flight. Defaults to
+	// DefaultUnseenPollConcurrency.
+	Concurrency int
+
+	// Timeout bounds every GetUnseenCount call. Zero means no timeout.
+	Timeout time.Duration
+
+	// OnError, when set, is called when a poll fails. A failing subscriber is
+	// backed off like an idle one.
+	OnError func(subscriberID string, err error)
+}
+
+// UnseenCountChange is published whenever the unseen count of a subscriber
+// changes, and once with the current count when a watch starts.
+type UnseenCountChange struct {
+	SubscriberId string    `json:"subscriberId"`
+	Count        int       `json:"count"`
+	At           time.Time `json:"at"`
+}
+
+type unseenWatch struct {
+	ch chan UnseenCountChange
+}
+
+type unseenEntry struct {
+	watches  map[*unseenWatch]bool
+	interval time.Duration
+	next     time.Time
+	polling  bool
+	last     *UnseenCountChange
+}
+
+// UnseenCountWatcher polls the unseen count of the watched subscribers and
+// publishes the changes on Go channels. A subscriber whose count does not
+// change is polled less and less often, down to MaxInterval, and goes back to
+// MinInterval as soon as its count changes or Poke is called.
+type UnseenCountWatcher struct {
+	client *APIClient
+	opts   UnseenCountWatcherOptions
+
+	mu      sync.Mutex
+	entries map[string]*unseenEntry
+	closed  bool
+	wake    chan struct{}
+}
+
+func NewUnseenCountWatcher(client *APIClient, opts UnseenCountWatcherOptions) *UnseenCountWatcher {
+	if opts.MinInterval <= 0 {
+		opts.MinInterval = DefaultUnseenMinInterval
+	}
+	if opts.MaxInterval <= 0 {
+		opts.MaxInterval = DefaultUnseenMaxInterval
+	}
+	if opts.MaxInterval < opts.MinInterval {
+		opts.MaxInterval = opts.MinInterval
+	}
+	if opts.Backoff < 1 {
+		opts.Backoff = DefaultUnseenBackoff
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultUnseenPollConcurrency
+	}
+
+	return &UnseenCountWatcher{
+		client:  client,
+		opts:    opts,
+		entries: make(map[string]*unseenEntry),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Watch starts watching a subscriber. The returned channel receives the
+// current count, then every change; a slow reader only misses intermediate
+// counts, never the latest one. The channel is closed by stop, or when Run
+// returns. A subscriber is polled as long as it has at least one watch.
+func (w *UnseenCountWatcher) Watch(subscriberID string) (changes <-chan UnseenCountChange, stop func()) {
+	watch := &unseenWatch{ch: make(chan UnseenCountChange, 1)}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		close(watch.ch)
+		return watch.ch, func() {}
+	}
+
+	entry, ok := w.entries[subscriberID]
+	if !ok {
+		entry = &unseenEntry{watches: make(map[*unseenWatch]bool), interval: w.opts.MinInterval}
+		w.entries[subscriberID] = entry
+		w.signal()
+	}
+	entry.watches[watch] = true
+	if entry.last != nil {
+		watch.ch <- *entry.last
+	}
+
+	var once sync.Once
+	return watch.ch, func() {
+		once.Do(func() { w.unwatch(subscriberID, watch) })
+	}
+}
+
+func (w *UnseenCountWatcher) unwatch(subscriberID string, watch *unseenWatch) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	entry, ok := w.entries[subscriberID]
+	if !ok || !entry.watches[watch] {
+		// Already closed by Run.
+		return
+	}
+
+	delete(entry.watches, watch)
+	close(watch.ch)
+	if len(entry.watches) == 0 {
+		delete(w.entries, subscriberID)
+	}
+}
+
+// Poke tells the watcher that the count of a subscriber is likely to change,
+// e.g. right after triggering a workflow, so that it is polled right away at
+// the shortest interval.
+func (w *UnseenCountWatcher) Poke(subscriberID string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	entry, ok := w.entries[subscriberID]
+	if !ok {
+		return
+	}
+
+	entry.interval = w.opts.MinInterval
+	entry.next = time.Time{}
+	w.signal()
+}
+
+// Interval returns the current polling interval of a subscriber, zero when it
+// is not watched.
+func (w *UnseenCountWatcher) Interval(subscriberID string) time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if entry, ok := w.entries[subscriberID]; ok {
+		return entry.interval
+	}
+	return 0
+}
+
+// signal wakes Run up to reschedule. The caller holds w.mu.
+func (w *UnseenCountWatcher) signal() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run polls the watched subscribers until the context is done, then closes
+// every watch channel and returns the context error. Run must only be called
+// once.
+func (w *UnseenCountWatcher) Run(ctx context.Context) error {
+	defer w.close()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	sem := make(chan struct{}, w.opts.Concurrency)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		due, wait := w.due(time.Now())
+		for _, subscriberID := range due {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+
+			wg.Add(1)
+			go func(subscriberID string) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				w.poll(ctx, subscriberID)
+			}(subscriberID)
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		case <-w.wake:
+		}
+	}
+}
+
+// due marks the subscribers to poll now and returns them with the time until
+// the next one is due.
+func (w *UnseenCountWatcher) due(now time.Time) ([]string, time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var due []string
+	wait := w.opts.MaxInterval
+	for subscriberID, entry := range w.entries {
+		if entry.polling {
+			continue
+		}
+		if !entry.next.After(now) {
+			entry.polling = true
+			due = append(due, subscriberID)
+			continue
+		}
+		if d := entry.next.Sub(now); d < wait {
+			wait = d
+		}
+	}
+
+	return due, wait
+}
+
+func (w *UnseenCountWatcher) poll(ctx context.Context, subscriberID string) {
+	if w.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.opts.Timeout)
+		defer cancel()
+	}
+
+	resp, err := w.client.SubscriberApi.GetUnseenCount(ctx, subscriberID, nil)
+	if err != nil {
+		err = errors.Wrapf(err, "unable to get unseen count of %s", subscriberID)
+		if ctx.Err() == nil && w.opts.OnError != nil {
+			w.opts.OnError(subscriberID, err)
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	defer w.signal()
+
+	entry, ok := w.entries[subscriberID]
+	if !ok {
+		// Unwatched while polling.
+		return
+	}
+	entry.polling = false
+
+	changed := err == nil && (entry.last == nil || entry.last.Count != resp.Data.Count)
+	if changed {
+		entry.interval = w.opts.MinInterval
+		entry.last = &UnseenCountChange{SubscriberId: subscriberID, Count: resp.Data.Count, At: time.Now()}
+		for watch := range entry.watches {
+			publishUnseenCount(watch.ch, *entry.last)
+		}
+	} else if !entry.next.IsZero() {
+		// A zero next means the subscriber was just watched or poked, which
+		// keeps the short interval for the following poll.
+		entry.interval = time.Duration(float64(entry.interval) * w.opts.Backoff)
+		if entry.interval > w.opts.MaxInterval {
+			entry.interval = w.opts.MaxInterval
+		}
+	}
+	entry.next = time.Now().Add(entry.interval)
+}
+
+// publishUnseenCount replaces the pending change of a watch, if any, so that
+// the poller never blocks on a slow reader.
+func publishUnseenCount(ch chan UnseenCountChange, change UnseenCountChange) {
+	select {
+	case <-ch:
+	default:
+	}
+	ch <- change
+}
+
+func (w *UnseenCountWatcher) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+	for subscriberID, entry := range w.entries {
+		for watch := range entry.watches {
+			close(watch.ch)
+		}
+		delete(w.entries, subscriberID)
+	}
+}
+
+// UnseenCountStreamHandler relays the unseen count of the authenticated
+// subscriber as Server-Sent Events, for browsers that cannot use the Novu
+// websocket. Every change is sent as an unseen_count event whose data is an
+// UnseenCountChange.
+type UnseenCountStreamHandler struct {
+	watcher      *UnseenCountWatcher
+	authenticate SubscriberAuthFunc
+
+	// KeepAlive is the interval of the comments sent to keep idle connections
+	// open. Defaults to DefaultUnseenStreamKeepAlive.
+	KeepAlive time.Duration
+}
+
+func NewUnseenCountStreamHandler(watcher *UnseenCountWatcher, authenticate SubscriberAuthFunc) *UnseenCountStreamHandler {
+	return &UnseenCountStreamHandler{
+		watcher:      watcher,
+		authenticate: authenticate,
+		KeepAlive:    DefaultUnseenStreamKeepAlive,
+	}
+}
+
+func (h *UnseenCountStreamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	subscriberID, ok := authenticateSubscriber(w, r, h.authenticate)
+	if !ok {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	changes, stop := h.watcher.Watch(subscriberID)
+	defer stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := h.KeepAlive
+	if keepAlive <= 0 {
+		keepAlive = DefaultUnseenStreamKeepAlive
+	}
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case change, ok := <-changes:
+			if !ok {
+				return
+			}
+			data, _ := json.Marshal(change)
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", unseenCountStreamEvent, data); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
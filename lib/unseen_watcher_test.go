@@ -0,0 +1,190 @@
+package lib_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/saeid-a/go-novu/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unseenServer answers the unseen count of every subscriber from counts. set
+// changes a count while the server runs.
+func unseenServer(t *testing.T, counts map[string]int) (server *httptest.Server, set func(subscriberID string, count int)) {
+	var mu sync.Mutex
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, "/v1/subscribers/"), "/notifications/unseen")
+
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(w, `{"data":{"count":%d}}`, counts[id])
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func(subscriberID string, count int) {
+		mu.Lock()
+		defer mu.Unlock()
+		counts[subscriberID] = count
+	}
+}
+
+// runUnseenWatcher runs a watcher until the end of the test.
+func runUnseenWatcher(t *testing.T, server *httptest.Server, opts lib.UnseenCountWatcherOptions) *lib.UnseenCountWatcher {
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(server.URL)})
+	watcher := lib.NewUnseenCountWatcher(c, opts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = watcher.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return watcher
+}
+
+func receiveUnseenCount(t *testing.T, changes <-chan lib.UnseenCountChange) lib.UnseenCountChange {
+	select {
+	case change, ok := <-changes:
+		require.True(t, ok, "channel closed")
+		return change
+	case <-time.After(time.Second):
+		require.FailNow(t, "no unseen count received")
+	}
+	return lib.UnseenCountChange{}
+}
+
+func TestUnseenCountWatcher_PublishesChanges(t *testing.T) {
+	server, set := unseenServer(t, map[string]int{"alice": 2})
+	watcher := runUnseenWatcher(t, server, lib.UnseenCountWatcherOptions{MinInterval: 10 * time.Millisecond})
+
+	changes, stop := watcher.Watch("alice")
+	assert.Equal(t, 2, receiveUnseenCount(t, changes).Count)
+
+	set("alice", 5)
+	change := receiveUnseenCount(t, changes)
+	assert.Equal(t, "alice", change.SubscriberId)
+	assert.Equal(t, 5, change.Count)
+
+	// A second watch starts with the current count.
+	other, stopOther := watcher.Watch("alice")
+	defer stopOther()
+	assert.Equal(t, 5, receiveUnseenCount(t, other).Count)
+
+	stop()
+	_, ok := <-changes
+	assert.False(t, ok)
+}
+
+func TestUnseenCountWatcher_AdaptiveInterval(t *testing.T) {
+	server, set := unseenServer(t, map[string]int{"alice": 1})
+	watcher := runUnseenWatcher(t, server, lib.UnseenCountWatcherOptions{
+		MinInterval: 5 * time.Millisecond,
+		MaxInterval: 80 * time.Millisecond,
+	})
+
+	changes, stop := watcher.Watch("alice")
+	defer stop()
+	receiveUnseenCount(t, changes)
+
+	require.Eventually(t, func() bool {
+		return watcher.Interval("alice") == 80*time.Millisecond
+	}, time.Second, time.Millisecond, "idle subscriber is backed off")
+
+	watcher.Poke("alice")
+	assert.Equal(t, 5*time.Millisecond, watcher.Interval("alice"))
+
+	require.Eventually(t, func() bool {
+		return watcher.Interval("alice") == 80*time.Millisecond
+	}, time.Second, time.Millisecond)
+
+	set("alice", 2)
+	receiveUnseenCount(t, changes)
+	assert.Less(t, watcher.Interval("alice"), 80*time.Millisecond, "activity tightens the interval")
+	assert.Zero(t, watcher.Interval("bob"))
+}
+
+func TestUnseenCountWatcher_RunClosesWatches(t *testing.T) {
+	server, _ := unseenServer(t, map[string]int{})
+	c := lib.NewAPIClient(novuApiKey, &lib.Config{BackendURL: lib.MustParseURL(server.URL)})
+	watcher := lib.NewUnseenCountWatcher(c, lib.UnseenCountWatcherOptions{})
+	changes, stop := watcher.Watch("alice")
+	defer stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, watcher.Run(ctx), context.Canceled)
+
+	_, ok := <-changes
+	assert.False(t, ok)
+
+	changes, _ = watcher.Watch("bob")
+	_, ok = <-changes
+	assert.False(t, ok, "watching a stopped watcher")
+}
+
+func TestUnseenCountStreamHandler(t *testing.T) {
+	server, set := unseenServer(t, map[string]int{"alice": 3})
+	watcher := runUnseenWatcher(t, server, lib.UnseenCountWatcherOptions{MinInterval: 10 * time.Millisecond})
+
+	handler := lib.NewUnseenCountStreamHandler(watcher, func(r *http.Request) (string, error) {
+		if r.Header.Get("Authorization") == "Bearer alice" {
+			return "alice", nil
+		}
+		return "", lib.ErrUnauthenticated
+	})
+	httpServer := httptest.NewServer(handler)
+	defer httpServer.Close()
+
+	resp, err := http.Get(httpServer.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	req, _ := http.NewRequest(http.MethodGet, httpServer.URL, nil)
+	req.Header.Set("Authorization", "Bearer alice")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := bufio.NewScanner(resp.Body)
+	readEvent := func() lib.UnseenCountChange {
+		var event, data string
+		for events.Scan() && events.Text() != "" {
+			line := events.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+		require.Equal(t, "unseen_count", event)
+
+		var change lib.UnseenCountChange
+		require.NoError(t, json.Unmarshal([]byte(data), &change))
+		return change
+	}
+
+	assert.Equal(t, 3, readEvent().Count)
+
+	set("alice", 4)
+	change := readEvent()
+	assert.Equal(t, "alice", change.SubscriberId)
+	assert.Equal(t, 4, change.Count)
+}
@@ -21,6 +21,10 @@ type QueryParam struct {
 	Value string
 }
 
+// GenerateQueryParamsFromStruct lists the non-zero string, bool and int fields
+// of a struct.
+//
+// Deprecated: use EncodeQuery, which also supports pointers, slices and times.
 func GenerateQueryParamsFromStruct[T interface{}](queryParamsStruct T) ([]QueryParam, error) {
 	var queryParamList []QueryParam = make([]QueryParam, 0)
 